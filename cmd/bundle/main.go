@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The April Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The bundle command manages the store of the package tarballs the baremetal providers
+// copy to the nodes. It runs in the manager container, where the store is mounted:
+//
+//	kubectl -n pml-system exec deploy/april -- /bundle import /tmp/bundle.tar.gz
+//	kubectl -n pml-system exec deploy/april -- /bundle list
+//
+// The store is seeded with the tarballs built into the image before the manager starts:
+//
+//	/bundle seed /provider/baremetal/res --store /store
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/pflag"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/res"
+)
+
+func main() {
+	fs := pflag.NewFlagSet("bundle", pflag.ExitOnError)
+	storeDir := fs.String("store", constants.SrcDir, "The dir the bundles are imported into.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n  bundle import BUNDLE.tar.gz [flags]\n  bundle seed DIR [flags]\n  bundle list [flags]\n\nFlags:\n%s", fs.FlagUsages())
+	}
+	if len(os.Args) < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	_ = fs.Parse(os.Args[2:])
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		err = importBundle(fs.Arg(0), *storeDir)
+	case "seed":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		err = seed(fs.Arg(0), *storeDir)
+	case "list":
+		err = list(*storeDir)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func importBundle(bundle, storeDir string) error {
+	artifacts, err := res.Import(bundle, storeDir)
+	if err != nil {
+		return err
+	}
+	for _, a := range artifacts {
+		fmt.Printf("imported %s %s for %s\n", a.Name, a.Version, a.Arch)
+	}
+
+	return nil
+}
+
+func seed(dir, storeDir string) error {
+	artifacts, err := res.Seed(dir, storeDir)
+	if err != nil {
+		return err
+	}
+	for _, a := range artifacts {
+		fmt.Printf("seeded %s %s for %s\n", a.Name, a.Version, a.Arch)
+	}
+
+	return nil
+}
+
+func list(storeDir string) error {
+	available, err := res.Available(storeDir)
+	if err != nil {
+		return err
+	}
+
+	table := uitable.New()
+	table.AddRow("PACKAGE", "ARCH", "VERSIONS")
+	names := make([]string, 0, len(available))
+	for name := range available {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		archs := make([]string, 0, len(available[name]))
+		for arch := range available[name] {
+			archs = append(archs, arch)
+		}
+		sort.Strings(archs)
+		for _, arch := range archs {
+			table.AddRow(name, arch, fmt.Sprint(available[name][arch]))
+		}
+	}
+	fmt.Println(table)
+
+	return nil
+}
@@ -17,6 +17,7 @@ require (
 	github.com/onsi/gomega v1.10.2 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/pkg/sftp v1.13.0
+	github.com/prometheus/client_golang v1.4.0
 	github.com/segmentio/ksuid v1.0.3
 	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/pflag v1.0.5
@@ -21,23 +21,35 @@ import (
 	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
 	"admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog"
+	"net/http"
+	"os"
 	"pml.io/april/pkg/controllers/cluster"
 	machinecontroller "pml.io/april/pkg/controllers/machine"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/res"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"time"
 
 	admiraltytclientset "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
 	admiraltyinformers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	clientset "pml.io/april/pkg/generated/clientset/versioned"
 	informers "pml.io/april/pkg/generated/informers/externalversions"
 	"pml.io/april/pkg/signals"
 )
 
+const (
+	metricsAddr         = ":8080"
+	defaultArtifactAddr = ":8081"
+)
+
 func main() {
 
 	klog.InitFlags(nil)
@@ -65,6 +77,17 @@ func main() {
 	//3. start controllers
 	startControllers(ctx, stopCh, agentCfg, cfg)
 
+	// 4. serve the metrics of the controllers
+	http.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(metricsAddr, nil); err != nil {
+			klog.Errorf("serve metrics error: %s", err)
+		}
+	}()
+
+	// 5. serve the package tarballs to the nodes
+	startArtifactServer()
+
 	// down.
 	<-stopCh
 }
@@ -123,3 +146,37 @@ func startControllers(ctx context.Context, stopCh <-chan struct{}, agentCfg agen
 	go func() { utilruntime.Must(clusterController.Run(2, stopCh)) }()
 
 }
+
+// startArtifactServer serves the package store to the nodes, which download the
+// tarballs from the server at APRIL_ARTIFACT_URL instead of having them pushed over
+// SFTP. The server is off when APRIL_ARTIFACT_URL is not set.
+func startArtifactServer() {
+	url := os.Getenv("APRIL_ARTIFACT_URL")
+	if url == "" {
+		return
+	}
+	token := os.Getenv("APRIL_ARTIFACT_TOKEN")
+	if token == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			klog.Fatalf("Error generating artifact token: %s", err.Error())
+		}
+		token = hex.EncodeToString(b)
+	}
+	addr := os.Getenv("APRIL_ARTIFACT_ADDR")
+	if addr == "" {
+		addr = defaultArtifactAddr
+	}
+	res.SetArtifactServer(url, token)
+
+	server := &http.Server{Addr: addr, Handler: res.NewServer(constants.SrcDir, token)}
+	go func() {
+		var err error
+		if cert, key := os.Getenv("APRIL_ARTIFACT_TLS_CERT"), os.Getenv("APRIL_ARTIFACT_TLS_KEY"); cert != "" && key != "" {
+			err = server.ListenAndServeTLS(cert, key)
+		} else {
+			err = server.ListenAndServe()
+		}
+		klog.Errorf("serve artifacts error: %s", err)
+	}()
+}
@@ -17,6 +17,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -40,6 +41,36 @@ type Cluster struct {
 type ClusterSpec struct {
 	Type             string            `json:"type" protobuf:"bytes,4,opt,name=type"`
 	KubeconfigSecret *KubeconfigSecret `json:"kubeconfigSecret,omitempty"`
+	// Version is the desired kubernetes version, changing it upgrades the cluster.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// MachineCount is the desired number of machines, changing it scales the cluster.
+	// +optional
+	MachineCount *int32 `json:"machineCount,omitempty"`
+	// Masters are the machines the control plane of a Baremetal cluster is built on,
+	// kubeadm init runs on the first one.
+	// +optional
+	Masters []ClusterMachine `json:"masters,omitempty"`
+	// HA makes the control plane of a Baremetal cluster highly available behind a virtual ip.
+	// +optional
+	HA *ClusterHA `json:"ha,omitempty"`
+	// +optional
+	ClusterCIDR string `json:"clusterCIDR,omitempty"`
+	// +optional
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+	// Registries configures the image registries of the masters of a Baremetal cluster.
+	// +optional
+	Registries []Registry `json:"registries,omitempty"`
+	// KubeProxy configures kube-proxy of a Baremetal cluster.
+	// +optional
+	KubeProxy *ClusterKubeProxy `json:"kubeProxy,omitempty"`
+	// VirtualKubelet customizes the virtual-kubelet deployment which represents the cluster.
+	// +optional
+	VirtualKubelet *VirtualKubeletSpec `json:"virtualKubelet,omitempty"`
+	// Addons are installed in a Baremetal cluster and kept at their version, removing
+	// an addon from the list uninstalls it.
+	// +optional
+	Addons []ClusterAddon `json:"addons,omitempty"`
 }
 
 type KubeconfigSecret struct {
@@ -50,6 +81,124 @@ type KubeconfigSecret struct {
 	Context string `json:"context,omitempty"`
 }
 
+// ClusterMachine is a master machine of a Baremetal cluster.
+type ClusterMachine struct {
+	IP       string `json:"ip"`
+	Port     int32  `json:"port"`
+	Username string `json:"username"`
+	// +optional
+	Password []byte `json:"password,omitempty"`
+	// +optional
+	PrivateKey []byte `json:"privateKey,omitempty"`
+	// +optional
+	PassPhrase []byte `json:"passPhrase,omitempty"`
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+// ClusterHA describes the virtual ip keepalived holds on the masters.
+type ClusterHA struct {
+	VIP string `json:"vip"`
+	// VRID is the virtual router id of keepalived, it defaults to the last byte of the vip.
+	// +optional
+	VRID *int32 `json:"vrid,omitempty"`
+	// Interface is the network interface the vip is bound to, it defaults to the one
+	// holding the ip of each master.
+	// +optional
+	Interface string `json:"interface,omitempty"`
+	// Registry is the repository the keepalived image is pulled from, e.g. the registry
+	// of an air-gapped site, it defaults to the public one.
+	// +optional
+	Registry string `json:"registry,omitempty"`
+}
+
+// ClusterKubeProxy is the proxy mode of kube-proxy and its conntrack settings.
+type ClusterKubeProxy struct {
+	// Mode is iptables or ipvs, it defaults to iptables.
+	// +optional
+	Mode string `json:"mode,omitempty"`
+	// IPVSScheduler is the scheduler of the ipvs mode, like rr, wrr, lc or sh, it defaults to rr.
+	// +optional
+	IPVSScheduler string `json:"ipvsScheduler,omitempty"`
+	// +optional
+	ConntrackMaxPerCore *int32 `json:"conntrackMaxPerCore,omitempty"`
+	// +optional
+	ConntrackMin *int32 `json:"conntrackMin,omitempty"`
+	// +optional
+	ConntrackTCPEstablishedTimeout *metav1.Duration `json:"conntrackTCPEstablishedTimeout,omitempty"`
+	// +optional
+	ConntrackTCPCloseWaitTimeout *metav1.Duration `json:"conntrackTCPCloseWaitTimeout,omitempty"`
+}
+
+// VirtualKubeletSpec describes the virtual-kubelet deployment of a cluster.
+// Empty fields fall back to the provider defaults.
+type VirtualKubeletSpec struct {
+	// +optional
+	Image string `json:"image,omitempty"`
+	// ExtraArgs overrides or extends the default virtual-kubelet flags, keyed by flag name.
+	// +optional
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// +optional
+	MetricsPort int32 `json:"metricsPort,omitempty"`
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+}
+
+// ClusterAddon is an addon of a cluster, like metrics-server or a CNI plugin.
+type ClusterAddon struct {
+	// Name is the name of one of the addons of the provider.
+	Name string `json:"name"`
+	// Version of the addon, the default version of the provider if empty, changing it
+	// upgrades the addon.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Values are passed to the manifest of the addon, overriding its defaults.
+	// +optional
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// AddonPhase is the state of an addon of a cluster.
+type AddonPhase string
+
+const (
+	// AddonRunning means that the workloads of the addon are ready.
+	AddonRunning AddonPhase = "Running"
+	// AddonUnhealthy means that the addon is installed but some of its workloads are not ready.
+	AddonUnhealthy AddonPhase = "Unhealthy"
+	// AddonFailed means that the addon couldn't be installed, upgraded or uninstalled.
+	AddonFailed AddonPhase = "Failed"
+)
+
+// AddonStatus is what was installed of an addon and how it was the last time it was checked.
+type AddonStatus struct {
+	Name string `json:"name"`
+	// Version is the version of the addon installed in the cluster.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Values are the values the addon was installed with.
+	// +optional
+	Values map[string]string `json:"values,omitempty"`
+	// +optional
+	Phase AddonPhase `json:"phase,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Last time the addon went to the phase.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
 // ClusterPhase defines the phases of platform constructor
 type ClusterPhase string
 
@@ -62,6 +211,10 @@ const (
 	ClusterFailed ClusterPhase = "Failed"
 	// MachineUpgrading means that the platform is in upgrading process.
 	ClusterUpgrading ClusterPhase = "Upgrading"
+	// ClusterUpscaling means that machines are being added to the cluster.
+	ClusterUpscaling ClusterPhase = "Upscaling"
+	// ClusterDownscaling means that machines are being removed from the cluster.
+	ClusterDownscaling ClusterPhase = "Downscaling"
 	// MachineTerminating is the terminating phases
 	ClusterTerminating ClusterPhase = "Terminating"
 )
@@ -100,6 +253,72 @@ type ClusterStatus struct {
 	// A brief CamelCase message indicating details about why the platform is in this state.
 	// +optional
 	Reason string `json:"reason,omitempty" protobuf:"bytes,5,opt,name=reason"`
+	// Version is the kubernetes version the cluster was last brought to.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// MachineCount is the number of machines the cluster was last scaled to.
+	// +optional
+	MachineCount int32 `json:"machineCount,omitempty"`
+	// Capability is what was discovered from the cluster the last time it was probed.
+	// +optional
+	Capability *ClusterCapability `json:"capability,omitempty"`
+	// Certificates tells when the control plane certificates of the masters expire.
+	// +optional
+	Certificates *ClusterCertificates `json:"certificates,omitempty"`
+	// Addons are the addons installed in the cluster.
+	// +optional
+	Addons []AddonStatus `json:"addons,omitempty"`
+	// MasterImages are the images prepared on each master before it joins the cluster.
+	// +optional
+	MasterImages []MasterImages `json:"masterImages,omitempty"`
+}
+
+// ClusterCapability describes the version and resources of a member cluster.
+type ClusterCapability struct {
+	// Last time the cluster was probed.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+	// +optional
+	Version string `json:"version,omitempty"`
+	// +optional
+	NodeCount int32 `json:"nodeCount,omitempty"`
+	// Allocatable is the sum of cpu, memory and gpu allocatable over the nodes of the cluster.
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+	// +optional
+	CNI string `json:"cni,omitempty"`
+	// ContainerRuntimes are the distinct container runtime versions reported by the nodes.
+	// +optional
+	ContainerRuntimes []string `json:"containerRuntimes,omitempty"`
+	// +optional
+	StorageClasses []string `json:"storageClasses,omitempty"`
+	// +optional
+	APIGroups []string `json:"apiGroups,omitempty"`
+}
+
+// ClusterCertificates is what was found on the masters the last time the certificates
+// were checked.
+type ClusterCertificates struct {
+	// +optional
+	LastCheckTime metav1.Time `json:"lastCheckTime,omitempty"`
+	// +optional
+	Items []CertificateExpiration `json:"items,omitempty"`
+}
+
+// CertificateExpiration tells when a certificate on a master expires.
+type CertificateExpiration struct {
+	// Machine is the IP of the master the certificate is on.
+	Machine string `json:"machine"`
+	// Name is the file name of the certificate, or of the kubeconfig it is embedded in.
+	Name     string      `json:"name"`
+	NotAfter metav1.Time `json:"notAfter"`
+}
+
+// MasterImages are the images prepared on a master.
+type MasterImages struct {
+	// Machine is the IP of the master.
+	Machine string        `json:"machine"`
+	Images  []ImageStatus `json:"images,omitempty"`
 }
 
 // +genclient:nonNamespaced
@@ -2,7 +2,9 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"pml.io/april/pkg/util/ssh"
 	"time"
 )
@@ -137,12 +139,83 @@ type MachineSpec struct {
 	CpuCore int `json:"cpucore" protobuf:"varint,6,opt,name=cpucore"`
 	// +optional
 	MemSize int `json:"memsize" protobuf:"varint,6,opt,name=memsize"`
+	// StorageSize is the storage the machine is offered with. It is not checked against
+	// the disks, the capacity provisioned is reported in status.storage.capacity.
 	// +optional
 	StorageSize int `json:"storageSize" protobuf:"varint,6,opt,name=storageSize"`
 	// +optional
 	PayType PayType `json:"payType,omitempty" protobuf:"bytes,12,opt,name=payType"`
 	// +optional
 	PayPrice int `json:"payPrice" protobuf:"varint,6,opt,name=payPrice"`
+	// Registries configures the image registries the machine pulls from.
+	// +optional
+	Registries []Registry `json:"registries,omitempty" protobuf:"bytes,13,rep,name=registries"`
+	// KubeletConfiguration is a partial kubelet.config.k8s.io/v1beta1 KubeletConfiguration
+	// overriding the one derived from the resources of the machine.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	KubeletConfiguration *runtime.RawExtension `json:"kubeletConfiguration,omitempty" protobuf:"bytes,14,opt,name=kubeletConfiguration"`
+	// GPU prepares the GPUs of the machine for the pods.
+	// +optional
+	GPU *MachineGPU `json:"gpu,omitempty" protobuf:"bytes,15,opt,name=gpu"`
+	// Storage selects the disks of a storage or hybrid machine which are offered to the
+	// cluster as local persistent volumes.
+	// +optional
+	Storage *MachineStorage `json:"storage,omitempty" protobuf:"bytes,16,opt,name=storage"`
+}
+
+// GPUVendor is the vendor of the GPUs of a machine.
+type GPUVendor string
+
+const (
+	// GPUVendorNvidia is the only vendor supported for now.
+	GPUVendorNvidia GPUVendor = "nvidia"
+)
+
+// MachineGPU describes how the GPUs of a machine are prepared.
+type MachineGPU struct {
+	// Enable installs the driver and the container runtime of the GPUs.
+	Enable bool `json:"enable" protobuf:"varint,1,opt,name=enable"`
+	// Vendor of the GPUs, defaults to nvidia.
+	// +optional
+	Vendor GPUVendor `json:"vendor,omitempty" protobuf:"bytes,2,opt,name=vendor,casttype=GPUVendor"`
+	// DriverVersion is the version of the driver, the default one of the store if empty.
+	// +optional
+	DriverVersion string `json:"driverVersion,omitempty" protobuf:"bytes,3,opt,name=driverVersion"`
+}
+
+// MachineStorage describes how the disks of a storage or hybrid machine are provisioned.
+type MachineStorage struct {
+	// Devices are the disks formatted and mounted for the local persistent volumes, like
+	// /dev/sdb. A disk with partitions or another filesystem is never formatted.
+	// +optional
+	Devices []string `json:"devices,omitempty" protobuf:"bytes,1,rep,name=devices"`
+	// FSType is the filesystem of the disks, xfs or ext4, defaults to xfs.
+	// +optional
+	FSType string `json:"fsType,omitempty" protobuf:"bytes,2,opt,name=fsType"`
+	// StorageClassName is the class of the local persistent volumes, defaults to
+	// april-local-storage.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty" protobuf:"bytes,3,opt,name=storageClassName"`
+}
+
+// Registry describes how a machine reaches an image registry.
+type Registry struct {
+	// Host is the registry, like docker.io or registry.example.com:5000.
+	Host string `json:"host" protobuf:"bytes,1,opt,name=host"`
+	// Mirrors are tried in order before the registry itself, like https://mirror.example.com.
+	// +optional
+	Mirrors []string `json:"mirrors,omitempty" protobuf:"bytes,2,rep,name=mirrors"`
+	// Insecure allows plain http and unverified certificates for the registry and its mirrors.
+	// +optional
+	Insecure bool `json:"insecure,omitempty" protobuf:"varint,3,opt,name=insecure"`
+	// CABundle is the PEM encoded CA the registry and its mirrors are verified with.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty" protobuf:"bytes,4,opt,name=caBundle"`
+	// AuthSecret is a kubernetes.io/dockerconfigjson Secret in the pml-system namespace
+	// holding the credentials of the registry and its mirrors.
+	// +optional
+	AuthSecret string `json:"authSecret,omitempty" protobuf:"bytes,5,opt,name=authSecret"`
 }
 
 // MachineAddress contains information for the platform's address.
@@ -186,6 +259,70 @@ type MachineStatus struct {
 	// +patchMergeKey=type
 	// +patchStrategy=merge
 	Addresses []MachineAddress `json:"addresses,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,6,rep,name=addresses"`
+	// Images prepared on the machine before it joins the cluster.
+	// +optional
+	Images []ImageStatus `json:"images,omitempty" protobuf:"bytes,7,rep,name=images"`
+	// GPU is the inventory of the GPUs of the machine.
+	// +optional
+	GPU *GPUStatus `json:"gpu,omitempty" protobuf:"bytes,8,opt,name=gpu"`
+	// Storage is the inventory of the disks of a storage or hybrid machine.
+	// +optional
+	Storage *StorageStatus `json:"storage,omitempty" protobuf:"bytes,9,opt,name=storage"`
+}
+
+// GPUStatus is the inventory of the GPUs of a machine.
+type GPUStatus struct {
+	Vendor GPUVendor `json:"vendor" protobuf:"bytes,1,opt,name=vendor,casttype=GPUVendor"`
+	// DriverVersion is the version of the driver installed.
+	// +optional
+	DriverVersion string `json:"driverVersion,omitempty" protobuf:"bytes,2,opt,name=driverVersion"`
+	// Count is the number of GPUs.
+	Count int32 `json:"count" protobuf:"varint,3,opt,name=count"`
+	// +optional
+	Devices []GPUDevice `json:"devices,omitempty" protobuf:"bytes,4,rep,name=devices"`
+}
+
+// GPUDevice is a GPU of a machine.
+type GPUDevice struct {
+	Index int32  `json:"index" protobuf:"varint,1,opt,name=index"`
+	UUID  string `json:"uuid" protobuf:"bytes,2,opt,name=uuid"`
+	// Model is the product name, like Tesla T4.
+	Model string `json:"model" protobuf:"bytes,3,opt,name=model"`
+	// MemoryMiB is the total memory of the GPU.
+	MemoryMiB int64 `json:"memoryMiB" protobuf:"varint,4,opt,name=memoryMiB"`
+}
+
+// StorageStatus is the inventory of the disks of a machine.
+type StorageStatus struct {
+	// Capacity is the total size of the disks provisioned as local persistent volumes.
+	Capacity resource.Quantity `json:"capacity" protobuf:"bytes,1,opt,name=capacity"`
+	// Disks are the disks of the machine which aren't used by the system.
+	// +optional
+	Disks []DiskStatus `json:"disks,omitempty" protobuf:"bytes,2,rep,name=disks"`
+}
+
+// DiskStatus is a disk of a machine, a provisioned disk has a mount path and a
+// persistent volume.
+type DiskStatus struct {
+	// Device is the path of the disk, like /dev/sdb.
+	Device string            `json:"device" protobuf:"bytes,1,opt,name=device"`
+	Size   resource.Quantity `json:"size" protobuf:"bytes,2,opt,name=size"`
+	// +optional
+	FSType string `json:"fsType,omitempty" protobuf:"bytes,3,opt,name=fsType"`
+	// +optional
+	MountPath string `json:"mountPath,omitempty" protobuf:"bytes,4,opt,name=mountPath"`
+	// PersistentVolume is the local persistent volume of the disk in the cluster.
+	// +optional
+	PersistentVolume string `json:"persistentVolume,omitempty" protobuf:"bytes,5,opt,name=persistentVolume"`
+}
+
+// ImageStatus tells whether an image is present on the machine.
+type ImageStatus struct {
+	Name  string `json:"name" protobuf:"bytes,1,opt,name=name"`
+	Ready bool   `json:"ready" protobuf:"varint,2,opt,name=ready"`
+	// Message is the error of loading or pulling the image.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,3,opt,name=message"`
 }
 
 // +genclient
@@ -289,3 +426,17 @@ func (in *MachineSpec) SSH() (*ssh.SSH, error) {
 	}
 	return ssh.New(sshConfig)
 }
+
+func (in *ClusterMachine) SSH() (*ssh.SSH, error) {
+	sshConfig := &ssh.Config{
+		User:        in.Username,
+		Host:        in.IP,
+		Port:        int(in.Port),
+		Password:    string(in.Password),
+		PrivateKey:  in.PrivateKey,
+		PassPhrase:  in.PassPhrase,
+		DialTimeOut: time.Second,
+		Retry:       0,
+	}
+	return ssh.New(sshConfig)
+}
@@ -2,8 +2,10 @@ package cluster
 
 import (
 	"admiralty.io/multicluster-scheduler/pkg/controller"
+	multiclusterClientset "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
 	"context"
 	"fmt"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -12,10 +14,12 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
+	"pml.io/april/pkg/apis/platform"
 	"pml.io/april/pkg/apis/platform/v1alpha1"
 	platformClientset "pml.io/april/pkg/generated/clientset/versioned"
 	platforminformers "pml.io/april/pkg/generated/informers/externalversions/platform/v1alpha1"
 	platformlisters "pml.io/april/pkg/generated/listers/platform/v1alpha1"
+	_ "pml.io/april/pkg/platform/provider/baremetal/cluster"
 	clusterprovider "pml.io/april/pkg/platform/provider/cluster"
 	_ "pml.io/april/pkg/platform/provider/imported/cluster"
 	"pml.io/april/pkg/platform/provider/imported/constants"
@@ -24,10 +28,14 @@ import (
 	"time"
 )
 
+// clusterFinalizer makes sure the resources of a cluster are cleaned up before it is removed.
+var clusterFinalizer = fmt.Sprintf("%s/%s", platform.GroupName, v1alpha1.ClusterFinalize)
+
 type reconciler struct {
-	kubeclientset     *kubernetes.Clientset
-	platformClientset platformClientset.Interface
-	clusterLister     platformlisters.ClusterLister
+	kubeclientset         *kubernetes.Clientset
+	platformClientset     platformClientset.Interface
+	multiclusterclientset multiclusterClientset.Interface
+	clusterLister         platformlisters.ClusterLister
 }
 
 // NewController returns a new Machine controller
@@ -35,11 +43,16 @@ func NewController(masterKubeclientset *kubernetes.Clientset, config *rest.Confi
 	clusterInformer platforminformers.ClusterInformer) *controller.Controller {
 	platformClientset, err := platformClientset.NewForConfig(config)
 	utilruntime.Must(err)
+
+	multiClientset, err := multiclusterClientset.NewForConfig(config)
+	utilruntime.Must(err)
+
 	// 1. construct TargetCluster Reconciler
 	r := &reconciler{
-		kubeclientset:     masterKubeclientset,
-		platformClientset: platformClientset,
-		clusterLister:     clusterInformer.Lister(),
+		kubeclientset:         masterKubeclientset,
+		platformClientset:     platformClientset,
+		multiclusterclientset: multiClientset,
+		clusterLister:         clusterInformer.Lister(),
 	}
 
 	//2. construct informer sync
@@ -71,6 +84,17 @@ func (r reconciler) Handle(key interface{}) (requeueAfter *time.Duration, err er
 		return nil, err
 	}
 	//2. Add default setting.
+	if targetCluster.DeletionTimestamp == nil && !hasFinalizer(targetCluster) {
+		targetCluster = targetCluster.DeepCopy()
+		targetCluster.Finalizers = append(targetCluster.Finalizers, clusterFinalizer)
+		// the update will trigger the next loop.
+		_, err = r.platformClientset.PlatformV1alpha1().Clusters().Update(ctx, targetCluster, metav1.UpdateOptions{})
+		return nil, err
+	}
+	if targetCluster.DeletionTimestamp != nil {
+		targetCluster = targetCluster.DeepCopy()
+		targetCluster.Status.Phase = v1alpha1.ClusterTerminating
+	}
 	if targetCluster.Status.Phase == "" {
 		targetCluster.Status.Phase = v1alpha1.ClusterInitializing
 	}
@@ -80,32 +104,41 @@ func (r reconciler) Handle(key interface{}) (requeueAfter *time.Duration, err er
 		err = r.onCreate(ctx, targetCluster)
 	case v1alpha1.ClusterRunning, v1alpha1.ClusterFailed:
 		err = r.onUpdate(ctx, targetCluster)
-	case v1alpha1.ClusterUpgrading:
+	case v1alpha1.ClusterUpgrading, v1alpha1.ClusterUpscaling, v1alpha1.ClusterDownscaling:
+		// each status update moves the pipeline one step further.
 		err = r.onUpdate(ctx, targetCluster)
 	case v1alpha1.ClusterTerminating:
 		log.FromContext(ctx).Info("TargetCluster has been terminated. Attempting to cleanup resources")
+		err = r.onDelete(ctx, targetCluster)
 	default:
 		log.FromContext(ctx).Info("unknown targetCluster phase", "status.phase", targetCluster.Status.Phase)
 	}
+	if err == nil && targetCluster.Status.Phase == v1alpha1.ClusterRunning {
+		// come back to refresh the capability of the cluster.
+		interval := constants.CapabilityProbeInterval
+		return &interval, nil
+	}
 
 	return nil, err
 }
 
 func (r reconciler) onCreate(ctx context.Context, targetCluster *v1alpha1.Cluster) error {
+	// the kubeconfig of a cluster built by april doesn't exist until its control plane is up.
 	targetCfg, err := r.getConfigFromKubeconfigConfigmapOrDie(ctx, targetCluster)
-	if err != nil {
+	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("ensureClusterCredentialExsit error: %w", err)
 	}
 	provider, err := clusterprovider.GetProvider(targetCluster.Spec.Type)
 	if err != nil {
 		return err
 	}
-	clusterWrapper, err := typesv1.GetCluster(targetCfg, targetCluster, r.kubeclientset)
+	clusterWrapper, err := typesv1.GetCluster(targetCfg, targetCluster, r.kubeclientset, r.multiclusterclientset)
 	if err != nil {
 		return err
 	}
+	clusterWrapper.MasterPlatformClientset = r.platformClientset
 
-	for targetCluster.Status.Phase == v1alpha1.ClusterInitializing {
+	for clusterWrapper.TargetCluster.Status.Phase == v1alpha1.ClusterInitializing {
 		err = provider.OnCreate(ctx, clusterWrapper)
 		if err != nil {
 			// Update status, ignore failure
@@ -122,18 +155,99 @@ func (r reconciler) onCreate(ctx context.Context, targetCluster *v1alpha1.Cluste
 }
 
 func (r reconciler) onUpdate(ctx context.Context, cluster *v1alpha1.Cluster) error {
-	return nil
+	targetCfg, err := r.getConfigFromKubeconfigConfigmapOrDie(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("ensureClusterCredentialExsit error: %w", err)
+	}
+	provider, err := clusterprovider.GetProvider(cluster.Spec.Type)
+	if err != nil {
+		return err
+	}
+	clusterWrapper, err := typesv1.GetCluster(targetCfg, cluster.DeepCopy(), r.kubeclientset, r.multiclusterclientset)
+	if err != nil {
+		return err
+	}
+	clusterWrapper.MasterPlatformClientset = r.platformClientset
+
+	err = provider.OnUpdate(ctx, clusterWrapper)
+	recordCertificates(cluster.Name, cluster.Status.Certificates, clusterWrapper.TargetCluster.Status.Certificates)
+	// only write back a changed status, or every status update would trigger another loop.
+	if !apiequality.Semantic.DeepEqual(cluster.Status, clusterWrapper.TargetCluster.Status) {
+		_, updateErr := r.platformClientset.PlatformV1alpha1().Clusters().UpdateStatus(ctx, clusterWrapper.TargetCluster, metav1.UpdateOptions{})
+		if err == nil {
+			err = updateErr
+		}
+	}
+
+	return err
+}
+
+func (r reconciler) onDelete(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	if !hasFinalizer(cluster) {
+		return nil
+	}
+	provider, err := clusterprovider.GetProvider(cluster.Spec.Type)
+	if err != nil {
+		return err
+	}
+	// the member cluster may be unreachable already, which should not block the deletion.
+	targetCfg, err := r.getConfigFromKubeconfigConfigmapOrDie(ctx, cluster)
+	if err != nil {
+		log.FromContext(ctx).Info("can't get config of the cluster being deleted", "cluster", cluster.Name, "error", err)
+	}
+	clusterWrapper, err := typesv1.GetCluster(targetCfg, cluster, r.kubeclientset, r.multiclusterclientset)
+	if err != nil {
+		return err
+	}
+	clusterWrapper.MasterPlatformClientset = r.platformClientset
+
+	if err := provider.OnDelete(ctx, clusterWrapper); err != nil {
+		// Update status, ignore failure
+		_, _ = r.platformClientset.PlatformV1alpha1().Clusters().UpdateStatus(ctx, clusterWrapper.TargetCluster, metav1.UpdateOptions{})
+		return err
+	}
+
+	recordCertificates(cluster.Name, cluster.Status.Certificates, nil)
+
+	cluster = clusterWrapper.TargetCluster
+	var finalizers []string
+	for _, f := range cluster.Finalizers {
+		if f != clusterFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	cluster.Finalizers = finalizers
+	_, err = r.platformClientset.PlatformV1alpha1().Clusters().Update(ctx, cluster, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func hasFinalizer(cluster *v1alpha1.Cluster) bool {
+	for _, f := range cluster.Finalizers {
+		if f == clusterFinalizer {
+			return true
+		}
+	}
+	return false
 }
 
 // getConfigFromKubeconfigConfigmapOrDie creates ClusterCredential for cluster if ClusterCredentialRef is nil.
 // TODO: add gc collector for clean non reference ClusterCredential.
 func (r reconciler) getConfigFromKubeconfigConfigmapOrDie(ctx context.Context, cluster *v1alpha1.Cluster) (*rest.Config, error) {
+	if cluster.Spec.KubeconfigSecret == nil {
+		return nil, fmt.Errorf("cluster %s has no kubeconfig", cluster.Name)
+	}
 	key := cluster.Spec.KubeconfigSecret.Key
 	if key == "" {
-		key = "kube.config"
+		key = constants.KubeconfigKey
 	}
 
 	credentialConfig, err := r.kubeclientset.CoreV1().ConfigMaps(constants.ClusterConfigNamespace).Get(ctx, cluster.Spec.KubeconfigSecret.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
 	cfg0, err := clientcmd.Load([]byte(credentialConfig.Data[key]))
 	if err != nil {
 		return nil, err
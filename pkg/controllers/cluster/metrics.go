@@ -0,0 +1,30 @@
+package cluster
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"pml.io/april/pkg/apis/platform/v1alpha1"
+)
+
+var certificateExpiration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "april_cluster_certificate_expiration_timestamp_seconds",
+	Help: "When a control plane certificate on a master of the cluster expires, in seconds since the epoch.",
+}, []string{"cluster", "machine", "certificate"})
+
+func init() {
+	prometheus.MustRegister(certificateExpiration)
+}
+
+// recordCertificates replaces the expiration series of the cluster with the certificates
+// found by the last check.
+func recordCertificates(cluster string, old, new *v1alpha1.ClusterCertificates) {
+	if old != nil {
+		for _, item := range old.Items {
+			certificateExpiration.DeleteLabelValues(cluster, item.Machine, item.Name)
+		}
+	}
+	if new != nil {
+		for _, item := range new.Items {
+			certificateExpiration.WithLabelValues(cluster, item.Machine, item.Name).Set(float64(item.NotAfter.Unix()))
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
 	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
 	"context"
+	"fmt"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -14,6 +16,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
+	"pml.io/april/pkg/apis/platform"
 	"pml.io/april/pkg/apis/platform/v1alpha1"
 	"pml.io/april/pkg/config/agent"
 	platformClientset "pml.io/april/pkg/generated/clientset/versioned"
@@ -28,6 +31,9 @@ import (
 
 const singletonName = "singleton"
 
+// machineFinalizer makes sure the access of a machine to its cluster is revoked before it is removed.
+var machineFinalizer = fmt.Sprintf("%s/%s", platform.GroupName, v1alpha1.MachineFinalize)
+
 type reconciler struct {
 	kubeclientset         *kubernetes.Clientset
 	platformClientset     platformClientset.Interface
@@ -92,11 +98,33 @@ func (r reconciler) Handle(key interface{}) (requeueAfter *time.Duration, err er
 		return nil, err
 	}
 	//2. Add default setting.
+	if machine.DeletionTimestamp == nil && !hasFinalizer(machine) {
+		machine = machine.DeepCopy()
+		machine.Finalizers = append(machine.Finalizers, machineFinalizer)
+		// the update will trigger the next loop.
+		_, err = r.platformClientset.PlatformV1alpha1().Machines().Update(ctx, machine, metav1.UpdateOptions{})
+		return nil, err
+	}
+	if machine.DeletionTimestamp != nil {
+		if !hasFinalizer(machine) {
+			return nil, nil
+		}
+		// a machine which never joined a cluster has nothing to clean up.
+		if machine.Spec.ClusterName == "" {
+			return nil, r.removeFinalizer(ctx, machine)
+		}
+		machine = machine.DeepCopy()
+		machine.Status.Phase = v1alpha1.MachineTerminating
+	}
 	if machine.Status.Phase == "" {
 		machine.Status.Phase = v1alpha1.MachineInitializing
 	}
 	//3. Schedule One cluster as the target to join into, Get the target cluster configuration.
 	targetConfig, err := r.getTargetClusterConfig(ctx, machine)
+	if err != nil && machine.Status.Phase == v1alpha1.MachineTerminating && errors.IsNotFound(err) {
+		klog.Infof("the cluster of machine '%s' is gone, nothing to clean up", machineName)
+		return nil, r.removeFinalizer(ctx, machine)
+	}
 	if err != nil {
 		klog.Info("can't get target cluster or get one cluster so we need go to next loop")
 		// In this case we'll handle the machine obj later
@@ -106,11 +134,12 @@ func (r reconciler) Handle(key interface{}) (requeueAfter *time.Duration, err er
 	switch machine.Status.Phase {
 	case v1alpha1.MachineInitializing:
 		err = r.onCreate(ctx, machine, targetConfig)
-	case v1alpha1.MachineRunning, v1alpha1.MachineFailed, v1alpha1.MachineUpgrading:
-		// TODO here. FIX ME FIX ME FIX ME!!!
-		klog.Info("Now finished", " phase is ", machine.Status.Phase)
+	case v1alpha1.MachineRunning, v1alpha1.MachineUpgrading:
+		err = r.onUpdate(ctx, machine, targetConfig)
+	case v1alpha1.MachineFailed:
+		klog.Infof("machine '%s' has failed, leave it alone", machineName)
 	case v1alpha1.MachineTerminating:
-		// TODO something here.
+		err = r.onDelete(ctx, machine, targetConfig)
 		if err == nil {
 			klog.Info("Machine has been successfully deleted")
 		}
@@ -167,6 +196,7 @@ func (r reconciler) onCreate(ctx context.Context, machine *v1alpha1.Machine, tar
 	if err != nil {
 		return err
 	}
+	clusterWrapper.MasterPlatformClientset = r.platformClientset
 
 	for machine.Status.Phase == v1alpha1.MachineInitializing {
 		err = provider.OnCreate(ctx, machine, clusterWrapper)
@@ -182,3 +212,72 @@ func (r reconciler) onCreate(ctx context.Context, machine *v1alpha1.Machine, tar
 		return nil
 	}
 }
+
+func (r reconciler) onUpdate(ctx context.Context, machine *v1alpha1.Machine, targetconfig *rest.Config) error {
+	provider, err := machineprovider.GetProvider(machine.Spec.Type)
+	if err != nil {
+		return err
+	}
+	clusterWrapper, err := innertypesv1.GetClusterByName(ctx, machine.Spec.ClusterName, targetconfig, r.kubeclientset)
+	if err != nil {
+		return err
+	}
+	clusterWrapper.MasterPlatformClientset = r.platformClientset
+
+	updated := machine.DeepCopy()
+	err = provider.OnUpdate(ctx, updated, clusterWrapper)
+	// only write back a changed status, or every status update would trigger another loop.
+	if !apiequality.Semantic.DeepEqual(machine.Status, updated.Status) {
+		_, updateErr := r.platformClientset.PlatformV1alpha1().Machines().UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+		if err == nil {
+			err = updateErr
+		}
+	}
+
+	return err
+}
+
+func (r reconciler) onDelete(ctx context.Context, machine *v1alpha1.Machine, targetconfig *rest.Config) error {
+	provider, err := machineprovider.GetProvider(machine.Spec.Type)
+	if err != nil {
+		return err
+	}
+	clusterWrapper, err := innertypesv1.GetClusterByName(ctx, machine.Spec.ClusterName, targetconfig, r.kubeclientset)
+	if err != nil {
+		return err
+	}
+	clusterWrapper.MasterPlatformClientset = r.platformClientset
+
+	if err := provider.OnDelete(ctx, machine, clusterWrapper); err != nil {
+		// Update status, ignore failure
+		_, _ = r.platformClientset.PlatformV1alpha1().Machines().UpdateStatus(ctx, machine, metav1.UpdateOptions{})
+		return err
+	}
+
+	return r.removeFinalizer(ctx, machine)
+}
+
+func (r reconciler) removeFinalizer(ctx context.Context, machine *v1alpha1.Machine) error {
+	machine = machine.DeepCopy()
+	var finalizers []string
+	for _, f := range machine.Finalizers {
+		if f != machineFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	machine.Finalizers = finalizers
+	_, err := r.platformClientset.PlatformV1alpha1().Machines().Update(ctx, machine, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func hasFinalizer(machine *v1alpha1.Machine) bool {
+	for _, f := range machine.Finalizers {
+		if f == machineFinalizer {
+			return true
+		}
+	}
+	return false
+}
@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
+	"pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta3"
 )
 
 // Scheme is the runtime.Scheme to which all kubeadm api types are registered.
@@ -39,5 +40,6 @@ func init() {
 // AddToScheme builds the kubeadm scheme using all known versions of the kubeadm api.
 func AddToScheme(scheme *runtime.Scheme) {
 	utilruntime.Must(v1beta2.AddToScheme(scheme))
-	utilruntime.Must(scheme.SetVersionPriority(v1beta2.SchemeGroupVersion))
+	utilruntime.Must(v1beta3.AddToScheme(scheme))
+	utilruntime.Must(scheme.SetVersionPriority(v1beta3.SchemeGroupVersion, v1beta2.SchemeGroupVersion))
 }
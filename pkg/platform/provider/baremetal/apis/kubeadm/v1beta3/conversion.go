@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	"pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
+)
+
+// ConvertInitConfiguration converts a v1beta2 InitConfiguration into this version.
+func ConvertInitConfiguration(in *v1beta2.InitConfiguration) *InitConfiguration {
+	in = in.DeepCopy()
+	return &InitConfiguration{
+		BootstrapTokens:  in.BootstrapTokens,
+		NodeRegistration: convertNodeRegistrationOptions(in.NodeRegistration),
+		LocalAPIEndpoint: in.LocalAPIEndpoint,
+		CertificateKey:   in.CertificateKey,
+	}
+}
+
+// ConvertClusterConfiguration converts a v1beta2 ClusterConfiguration into this version,
+// UseHyperKubeImage and the DNS type are dropped as kubeadm only supports CoreDNS
+// without hyperkube since v1.22.
+func ConvertClusterConfiguration(in *v1beta2.ClusterConfiguration) *ClusterConfiguration {
+	in = in.DeepCopy()
+	return &ClusterConfiguration{
+		Etcd:                 in.Etcd,
+		Networking:           in.Networking,
+		KubernetesVersion:    in.KubernetesVersion,
+		ControlPlaneEndpoint: in.ControlPlaneEndpoint,
+		APIServer:            in.APIServer,
+		ControllerManager:    in.ControllerManager,
+		Scheduler:            in.Scheduler,
+		DNS:                  DNS{ImageMeta: in.DNS.ImageMeta},
+		CertificatesDir:      in.CertificatesDir,
+		ImageRepository:      in.ImageRepository,
+		FeatureGates:         in.FeatureGates,
+		ClusterName:          in.ClusterName,
+	}
+}
+
+// ConvertJoinConfiguration converts a v1beta2 JoinConfiguration into this version.
+func ConvertJoinConfiguration(in *v1beta2.JoinConfiguration) *JoinConfiguration {
+	in = in.DeepCopy()
+	return &JoinConfiguration{
+		NodeRegistration: convertNodeRegistrationOptions(in.NodeRegistration),
+		CACertPath:       in.CACertPath,
+		Discovery:        in.Discovery,
+		ControlPlane:     in.ControlPlane,
+	}
+}
+
+func convertNodeRegistrationOptions(in v1beta2.NodeRegistrationOptions) NodeRegistrationOptions {
+	return NodeRegistrationOptions{
+		Name:                  in.Name,
+		CRISocket:             in.CRISocket,
+		Taints:                in.Taints,
+		KubeletExtraArgs:      in.KubeletExtraArgs,
+		IgnorePreflightErrors: in.IgnorePreflightErrors,
+	}
+}
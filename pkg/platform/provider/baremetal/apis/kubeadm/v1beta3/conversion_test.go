@@ -0,0 +1,91 @@
+package v1beta3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
+)
+
+var nodeRegistration = v1beta2.NodeRegistrationOptions{
+	Name:                  "node1",
+	CRISocket:             "/var/run/dockershim.sock",
+	Taints:                []v1.Taint{{Key: "node-role.kubernetes.io/master", Effect: v1.TaintEffectNoSchedule}},
+	KubeletExtraArgs:      map[string]string{"node-ip": "10.0.0.1"},
+	IgnorePreflightErrors: []string{"ImagePull"},
+}
+
+func TestConvertInitConfiguration(t *testing.T) {
+	in := &v1beta2.InitConfiguration{
+		BootstrapTokens:  []v1beta2.BootstrapToken{{Description: "april"}},
+		NodeRegistration: nodeRegistration,
+		LocalAPIEndpoint: v1beta2.APIEndpoint{AdvertiseAddress: "10.0.0.1", BindPort: 6443},
+		CertificateKey:   "key",
+	}
+	out := ConvertInitConfiguration(in)
+
+	assert.Equal(t, in.BootstrapTokens, out.BootstrapTokens)
+	assert.Equal(t, in.LocalAPIEndpoint, out.LocalAPIEndpoint)
+	assert.Equal(t, "key", out.CertificateKey)
+	assert.Equal(t, NodeRegistrationOptions{
+		Name:                  "node1",
+		CRISocket:             "/var/run/dockershim.sock",
+		Taints:                nodeRegistration.Taints,
+		KubeletExtraArgs:      nodeRegistration.KubeletExtraArgs,
+		IgnorePreflightErrors: nodeRegistration.IgnorePreflightErrors,
+	}, out.NodeRegistration)
+
+	// the converted config is a copy.
+	out.NodeRegistration.Taints[0].Key = "changed"
+	assert.Equal(t, "node-role.kubernetes.io/master", in.NodeRegistration.Taints[0].Key)
+}
+
+func TestConvertClusterConfiguration(t *testing.T) {
+	in := &v1beta2.ClusterConfiguration{
+		Networking:           v1beta2.Networking{PodSubnet: "10.244.0.0/16", ServiceSubnet: "10.96.0.0/12"},
+		KubernetesVersion:    "v1.22.2",
+		ControlPlaneEndpoint: "10.0.0.100:6443",
+		DNS:                  v1beta2.DNS{Type: v1beta2.CoreDNS, ImageMeta: v1beta2.ImageMeta{ImageTag: "v1.8.4"}},
+		CertificatesDir:      "/etc/kubernetes/pki",
+		ImageRepository:      "registry.local",
+		UseHyperKubeImage:    true,
+		FeatureGates:         map[string]bool{"IPv6DualStack": true},
+		ClusterName:          "demo",
+	}
+	out := ConvertClusterConfiguration(in)
+
+	assert.Equal(t, in.Networking, out.Networking)
+	assert.Equal(t, "v1.22.2", out.KubernetesVersion)
+	assert.Equal(t, "10.0.0.100:6443", out.ControlPlaneEndpoint)
+	assert.Equal(t, DNS{ImageMeta: ImageMeta{ImageTag: "v1.8.4"}}, out.DNS)
+	assert.Equal(t, "/etc/kubernetes/pki", out.CertificatesDir)
+	assert.Equal(t, "registry.local", out.ImageRepository)
+	assert.Equal(t, in.FeatureGates, out.FeatureGates)
+	assert.Equal(t, "demo", out.ClusterName)
+}
+
+func TestConvertJoinConfiguration(t *testing.T) {
+	in := &v1beta2.JoinConfiguration{
+		NodeRegistration: nodeRegistration,
+		CACertPath:       "/etc/kubernetes/pki/ca.crt",
+		Discovery: v1beta2.Discovery{
+			BootstrapToken: &v1beta2.BootstrapTokenDiscovery{Token: "abcdef.0123456789abcdef", APIServerEndpoint: "10.0.0.100:6443"},
+		},
+		ControlPlane: &v1beta2.JoinControlPlane{
+			LocalAPIEndpoint: v1beta2.APIEndpoint{AdvertiseAddress: "10.0.0.2"},
+			CertificateKey:   "key",
+		},
+	}
+	out := ConvertJoinConfiguration(in)
+
+	assert.Equal(t, in.NodeRegistration.Taints, out.NodeRegistration.Taints)
+	assert.Equal(t, in.NodeRegistration.KubeletExtraArgs, out.NodeRegistration.KubeletExtraArgs)
+	assert.Equal(t, "/etc/kubernetes/pki/ca.crt", out.CACertPath)
+	assert.Equal(t, in.Discovery, out.Discovery)
+	assert.Equal(t, "key", out.ControlPlane.CertificateKey)
+	assert.Equal(t, "10.0.0.2", out.ControlPlane.LocalAPIEndpoint.AdvertiseAddress)
+
+	out.ControlPlane.CertificateKey = "changed"
+	assert.Equal(t, "key", in.ControlPlane.CertificateKey)
+}
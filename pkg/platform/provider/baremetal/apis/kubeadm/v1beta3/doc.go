@@ -0,0 +1,40 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +k8s:defaulter-gen=TypeMeta
+// +groupName=kubeadm.k8s.io
+// +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm
+
+// Package v1beta3 defines the v1beta3 version of the kubeadm configuration file format.
+// This version improves on the v1beta2 format by fixing some minor issues and adding a few new fields.
+//
+// A list of changes since v1beta2:
+//	- The deprecated "ClusterConfiguration.useHyperKubeImage" field has been removed.
+//	  Kubeadm no longer supports the hyperkube image.
+//	- The "ClusterConfiguration.DNS.Type" field has been removed since CoreDNS is the only supported
+//	  DNS server type by kubeadm.
+//	- Include "datapolicy" tags on the fields that hold secrets.
+//	- The "ClusterStatus" structure has been removed.
+//	- Add "InitConfiguration.SkipPhases" and "JoinConfiguration.SkipPhases".
+//	- Add "InitConfiguration.Patches" and "JoinConfiguration.Patches".
+//	- Add "NodeRegistrationOptions.ImagePullPolicy".
+//	See the Kubernetes 1.22 changelog for further details.
+//
+// Only the types which differ from v1beta2 are declared here, the unchanged ones are aliases of
+// their v1beta2 counterparts. ConvertInitConfiguration, ConvertClusterConfiguration and
+// ConvertJoinConfiguration translate the v1beta2 objects april builds into this version.
+package v1beta3 // import "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta3"
@@ -0,0 +1,178 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/addons"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/apiclient"
+	"pml.io/april/pkg/util/log"
+)
+
+// EnsureAddons installs the addons of the cluster, upgrades the ones whose version or
+// values changed and uninstalls the ones removed from the spec but the CNI. The status
+// of each addon tells what is installed and whether its workloads are ready.
+func (p *Provider) EnsureAddons(ctx context.Context, c *typesv1.Cluster) error {
+	spec, status := c.TargetCluster.Spec.Addons, c.TargetCluster.Status.Addons
+	if len(spec) == 0 && len(status) == 0 {
+		return nil
+	}
+	client, err := kubernetes.NewForConfig(c.TargetConfig)
+	if err != nil {
+		return err
+	}
+	applier, err := apiclient.NewApplier(c.TargetConfig, "")
+	if err != nil {
+		return err
+	}
+
+	last := make(map[string]*platformv1.AddonStatus, len(status))
+	for i := range status {
+		last[status[i].Name] = &status[i]
+	}
+	var (
+		statuses []platformv1.AddonStatus
+		errs     []error
+	)
+	for _, one := range spec {
+		s, err := ensureAddon(ctx, client, applier, one, last[one.Name], clusterValues(c))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		statuses = append(statuses, s)
+		delete(last, one.Name)
+	}
+	for _, s := range status {
+		if _, ok := last[s.Name]; !ok {
+			continue
+		}
+		if a, err := addons.Get(s.Name); err == nil && a.CNI {
+			// the pods of the cluster lose their network without the CNI.
+			log.FromContext(ctx).Info("Keep the CNI addon removed from the spec", "cluster", c.ClusterName, "addon", s.Name)
+			statuses = append(statuses, s)
+			continue
+		}
+		log.FromContext(ctx).Info("Uninstall addon", "cluster", c.ClusterName, "addon", s.Name)
+		if err := uninstallAddon(ctx, applier, s, clusterValues(c)); err != nil {
+			// keep the addon until it is uninstalled.
+			setAddonPhase(&s, platformv1.AddonFailed, err.Error())
+			statuses = append(statuses, s)
+			errs = append(errs, err)
+		}
+	}
+	c.TargetCluster.Status.Addons = statuses
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// ensureAddon installs the addon unless it is installed at the version with the
+// values already, and checks its workloads.
+func ensureAddon(ctx context.Context, client kubernetes.Interface, applier *apiclient.Applier,
+	spec platformv1.ClusterAddon, last *platformv1.AddonStatus, clusterValues map[string]string) (platformv1.AddonStatus, error) {
+	status := platformv1.AddonStatus{Name: spec.Name}
+	if last != nil {
+		status = *last.DeepCopy()
+	}
+	a, err := addons.Get(spec.Name)
+	if err != nil {
+		setAddonPhase(&status, platformv1.AddonFailed, err.Error())
+		return status, err
+	}
+	version, err := a.Version(spec.Version)
+	if err != nil {
+		setAddonPhase(&status, platformv1.AddonFailed, err.Error())
+		return status, err
+	}
+	if last == nil || last.Phase == platformv1.AddonFailed || last.Version != version || !reflect.DeepEqual(last.Values, spec.Values) {
+		err := a.Install(ctx, applier, a.Option(version, spec.Values, clusterValues))
+		if err != nil {
+			setAddonPhase(&status, platformv1.AddonFailed, err.Error())
+			return status, err
+		}
+		status.Version = version
+		status.Values = spec.DeepCopy().Values
+	}
+	if err := a.Check(ctx, client); err != nil {
+		setAddonPhase(&status, platformv1.AddonUnhealthy, err.Error())
+	} else {
+		setAddonPhase(&status, platformv1.AddonRunning, "")
+	}
+
+	return status, nil
+}
+
+// uninstallAddon uninstalls the addon as it was installed.
+func uninstallAddon(ctx context.Context, applier *apiclient.Applier, status platformv1.AddonStatus, clusterValues map[string]string) error {
+	a, err := addons.Get(status.Name)
+	if err != nil {
+		return err
+	}
+
+	return a.Uninstall(ctx, applier, a.Option(status.Version, status.Values, clusterValues))
+}
+
+// setAddonPhase sets the phase of the addon, the transition time only changes along
+// with the phase so that an unchanged addon leaves the status of the cluster alone.
+func setAddonPhase(status *platformv1.AddonStatus, phase platformv1.AddonPhase, message string) {
+	if status.Phase != phase {
+		status.LastTransitionTime = metav1.Now()
+	}
+	status.Phase = phase
+	status.Message = message
+}
+
+// clusterValues are the values of the cluster the manifests of the addons are
+// rendered with.
+func clusterValues(c *typesv1.Cluster) map[string]string {
+	return map[string]string{
+		"ClusterCIDR": clusterCIDR(c),
+	}
+}
+
+// clusterAddon returns the addon of the name in the spec of the cluster, nil if the
+// cluster doesn't list it.
+func clusterAddon(c *typesv1.Cluster, name string) *platformv1.ClusterAddon {
+	for i := range c.TargetCluster.Spec.Addons {
+		if c.TargetCluster.Spec.Addons[i].Name == name {
+			return &c.TargetCluster.Spec.Addons[i]
+		}
+	}
+
+	return nil
+}
+
+func validateAddons(specAddons []platformv1.ClusterAddon, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := make(map[string]bool)
+	for i, one := range specAddons {
+		if names[one.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i).Child("name"), one.Name))
+			continue
+		}
+		names[one.Name] = true
+		a, err := addons.Get(one.Name)
+		if err != nil {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i).Child("name"), one.Name, addons.Names()))
+			continue
+		}
+		if _, err := a.Version(one.Version); err != nil {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i).Child("version"), one.Version, a.Versions()))
+		}
+		for _, k := range a.InvalidValues(one.Values) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i).Child("values").Key(k), one.Values[k], a.ValidValues[k]))
+		}
+		if a.CNI && one.Name != addons.Flannel {
+			// the clusters are created with flannel.
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i).Child("name"), one.Name, []string{addons.Flannel}))
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,104 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/log"
+)
+
+// EnsureCertsRenewed checks the certs of the masters every CertsCheckInterval, and
+// renews them one master at a time once any of them is about to expire. The kubeconfig
+// of the cluster is exported again afterwards, as its client cert is renewed too.
+func (p *Provider) EnsureCertsRenewed(ctx context.Context, c *typesv1.Cluster) error {
+	last := c.TargetCluster.Status.Certificates
+	if last != nil && time.Since(last.LastCheckTime.Time) < constants.CertsCheckInterval {
+		return nil
+	}
+	completeCluster(c)
+
+	certificates := &platformv1.ClusterCertificates{LastCheckTime: metav1.Now()}
+	renewed := false
+	for i := range c.TargetCluster.Spec.Masters {
+		master := &c.TargetCluster.Spec.Masters[i]
+		s, err := master.SSH()
+		if err != nil {
+			return err
+		}
+		expiration, err := kubeadm.GetCertsExpiration(s)
+		if err != nil {
+			return errors.Wrapf(err, "master %s", master.IP)
+		}
+		if needRenewCerts(expiration) {
+			log.FromContext(ctx).Info("Renew certs", "cluster", c.ClusterName, "master", master.IP)
+			if err := kubeadm.RenewCerts(s, c.K8sVersionsWithV); err != nil {
+				return errors.Wrapf(err, "renew certs of master %s", master.IP)
+			}
+			// the masters behind the vip must not restart all at once.
+			if err := waitControlPlane(s); err != nil {
+				return errors.Wrapf(err, "master %s", master.IP)
+			}
+			renewed = true
+			expiration, err = kubeadm.GetCertsExpiration(s)
+			if err != nil {
+				return errors.Wrapf(err, "master %s", master.IP)
+			}
+		}
+		for name, notAfter := range expiration {
+			certificates.Items = append(certificates.Items, platformv1.CertificateExpiration{
+				Machine:  master.IP,
+				Name:     name,
+				NotAfter: metav1.NewTime(notAfter),
+			})
+		}
+	}
+	sort.Slice(certificates.Items, func(i, j int) bool {
+		a, b := certificates.Items[i], certificates.Items[j]
+		if a.Machine != b.Machine {
+			return a.Machine < b.Machine
+		}
+		return a.Name < b.Name
+	})
+	c.TargetCluster.Status.Certificates = certificates
+
+	if renewed {
+		return p.EnsureKubeconfigExported(ctx, c)
+	}
+
+	return nil
+}
+
+func needRenewCerts(expiration map[string]time.Time) bool {
+	for _, notAfter := range expiration {
+		if time.Until(notAfter) < constants.RenewCertsTimeThreshold {
+			return true
+		}
+	}
+
+	return false
+}
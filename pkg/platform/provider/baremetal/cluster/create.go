@@ -0,0 +1,482 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/kubernetes/cmd/kubeadm/app/phases/copycerts"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	baremetalmachine "pml.io/april/pkg/platform/provider/baremetal/machine"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/addons"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/firewall"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/keepalived"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeproxy"
+	"pml.io/april/pkg/platform/provider/baremetal/preflight"
+	importedconstants "pml.io/april/pkg/platform/provider/imported/constants"
+	machineprovider "pml.io/april/pkg/platform/provider/machine"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/apiclient"
+	"pml.io/april/pkg/util/ssh"
+)
+
+func (p *Provider) EnsureClusterValidated(ctx context.Context, c *typesv1.Cluster) error {
+	return p.validate(c).ToAggregate()
+}
+
+func (p *Provider) EnsureOSDetected(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureOSDetected)
+}
+
+func (p *Provider) EnsureFirewall(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, func(ctx context.Context, machine *platformv1.Machine, c *typesv1.Cluster) error {
+		s, err := machine.Spec.SSH()
+		if err != nil {
+			return err
+		}
+		rules, err := baremetalmachine.CNIRules(c)
+		if err != nil {
+			return err
+		}
+		return firewall.Open(s, append(firewall.ControlPlaneRules(), rules...))
+	})
+}
+
+// EnsureFirewallReverted removes the rules april added to the firewalls of the masters.
+func (p *Provider) EnsureFirewallReverted(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureFirewallReverted)
+}
+
+// EnsureGPUAddonRemoved removes the GPU addon once no node with GPUs remains, in case
+// the machines which left last did not.
+func (p *Provider) EnsureGPUAddonRemoved(ctx context.Context, c *typesv1.Cluster) error {
+	return baremetalmachine.RemoveUnusedGPUAddon(ctx, c)
+}
+
+func (p *Provider) EnsureClean(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureClean)
+}
+
+func (p *Provider) EnsureInitAPIServerHost(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureInitAPIServerHost)
+}
+
+func (p *Provider) EnsureKernelModule(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureKernelModule)
+}
+
+func (p *Provider) EnsureSysctl(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureSysctl)
+}
+
+func (p *Provider) EnsureDisableSwap(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureDisableSwap)
+}
+
+func (p *Provider) EnsureManifestDir(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureManifestDir)
+}
+
+// EnsureKeepalived puts keepalived on the masters of a HA cluster, the master with
+// the highest priority holds the vip as long as its kube-apiserver is alive.
+func (p *Provider) EnsureKeepalived(ctx context.Context, c *typesv1.Cluster) error {
+	ha := c.TargetCluster.Spec.HA
+	if ha == nil {
+		return nil
+	}
+	vrid := keepalived.DefaultVRID(ha.VIP)
+	if ha.VRID != nil {
+		vrid = *ha.VRID
+	}
+
+	masters := c.TargetCluster.Spec.Masters
+	for i := range masters {
+		var peers []string
+		for j := range masters {
+			if j != i {
+				peers = append(peers, masters[j].IP)
+			}
+		}
+		s, err := masters[i].SSH()
+		if err != nil {
+			return err
+		}
+		option := &keepalived.Option{
+			IP:        masters[i].IP,
+			Peers:     peers,
+			VIP:       ha.VIP,
+			VRID:      vrid,
+			Priority:  100 - i,
+			Interface: ha.Interface,
+			Image:     keepalived.Image(ha.Registry),
+		}
+		if err := keepalived.Install(s, option); err != nil {
+			return errors.Wrapf(err, "master %s", masters[i].IP)
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) EnsurePreflight(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, func(ctx context.Context, machine *platformv1.Machine, c *typesv1.Cluster) error {
+		machineSSH, err := machine.Spec.SSH()
+		if err != nil {
+			return err
+		}
+
+		release, err := baremetalmachine.KubernetesRelease(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		modules := kubeproxy.KernelModules(kubeproxy.Configuration(c.TargetCluster.Spec.KubeProxy))
+		return preflight.RunMasterChecks(c.ClusterName, machineSSH, modules, c.K8sVersionsWithV, release)
+	})
+}
+
+func (p *Provider) EnsureRegistries(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureRegistries)
+}
+
+func (p *Provider) EnsureDocker(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureDocker)
+}
+
+func (p *Provider) EnsureKubelet(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureKubelet)
+}
+
+func (p *Provider) EnsureCNIPlugins(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureCNIPlugins)
+}
+
+func (p *Provider) EnsureConntrackTools(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureConntrackTools)
+}
+
+func (p *Provider) EnsureKubeadm(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureKubeadm)
+}
+
+// EnsureImages prepares the images on the masters, the result of every image is
+// recorded in the status of the cluster by master.
+func (p *Provider) EnsureImages(ctx context.Context, c *typesv1.Cluster) error {
+	c.TargetCluster.Status.MasterImages = nil
+	return p.onMasters(ctx, c, func(ctx context.Context, machine *platformv1.Machine, c *typesv1.Cluster) error {
+		err := p.machine.EnsureImages(ctx, machine, c)
+		c.TargetCluster.Status.MasterImages = append(c.TargetCluster.Status.MasterImages, platformv1.MasterImages{
+			Machine: machine.Spec.IP,
+			Images:  machine.Status.Images,
+		})
+		return err
+	})
+}
+
+func (p *Provider) EnsureKubeadmInitPreflightPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "preflight")
+}
+
+func (p *Provider) EnsureKubeadmInitKubeletStartPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "kubelet-start")
+}
+
+func (p *Provider) EnsureKubeadmInitCertsPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "certs all")
+}
+
+func (p *Provider) EnsureKubeadmInitKubeConfigPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "kubeconfig all")
+}
+
+func (p *Provider) EnsureKubeadmInitControlPlanePhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "control-plane all")
+}
+
+func (p *Provider) EnsureKubeadmInitEtcdPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "etcd local")
+}
+
+// EnsureKubeadmInitWaitControlPlanePhase waits for the static pods of the first master
+// to serve, the wait-control-plane phase of kubeadm can't be run alone.
+func (p *Provider) EnsureKubeadmInitWaitControlPlanePhase(ctx context.Context, c *typesv1.Cluster) error {
+	s, err := c.TargetCluster.Spec.Masters[0].SSH()
+	if err != nil {
+		return err
+	}
+
+	return waitControlPlane(s)
+}
+
+// waitControlPlane waits for the kube-apiserver on the master to serve.
+func waitControlPlane(s ssh.Interface) error {
+	cmd := fmt.Sprintf("kubectl --kubeconfig=%s get --raw=/healthz", constants.AdminKubeConfigFileName)
+	err := wait.PollImmediate(5*time.Second, 5*time.Minute, func() (bool, error) {
+		_, err := s.CombinedOutput(cmd)
+		return err == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for the control plane error: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Provider) EnsureKubeadmInitUploadConfigPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "upload-config all")
+}
+
+func (p *Provider) EnsureKubeadmInitMarkControlPlanePhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "mark-control-plane")
+}
+
+func (p *Provider) EnsureKubeadmInitBootstrapTokenPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "bootstrap-token")
+}
+
+func (p *Provider) EnsureKubeadmInitAddonPhase(ctx context.Context, c *typesv1.Cluster) error {
+	return p.initPhase(c, "addon all")
+}
+
+// EnsureKubeconfigExported stores the admin kubeconfig of the new cluster into the
+// config map referenced by the cluster, which is where april reads the kubeconfig
+// of every cluster from. The config map is owned by the cluster.
+func (p *Provider) EnsureKubeconfigExported(ctx context.Context, c *typesv1.Cluster) error {
+	completeCluster(c)
+	s, err := c.TargetCluster.Spec.Masters[0].SSH()
+	if err != nil {
+		return err
+	}
+	data, err := s.ReadFile(constants.AdminKubeConfigFileName)
+	if err != nil {
+		return err
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return err
+	}
+	// the api server host name only resolves on the machines of the cluster.
+	for _, cluster := range config.Clusters {
+		cluster.Server = fmt.Sprintf("https://%s:%d", c.MasterIp, constants.APIServerPort)
+	}
+	data, err = clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+
+	key := c.TargetCluster.Spec.KubeconfigSecret.Key
+	if key == "" {
+		key = importedconstants.KubeconfigKey
+	}
+	ownerRef := metav1.NewControllerRef(c.TargetCluster, platformv1.SchemeGroupVersion.WithKind("Cluster"))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       importedconstants.ClusterConfigNamespace,
+			Name:            c.TargetCluster.Spec.KubeconfigSecret.Name,
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Data: map[string]string{key: string(data)},
+	}
+	if err := apiclient.CreateOrUpdateConfigMap(ctx, c.MasterKubeclientset, cm); err != nil {
+		return err
+	}
+
+	c.TargetConfig, err = clientcmd.RESTConfigFromKubeConfig(data)
+	return err
+}
+
+// EnsureKubeletConfiguration derives the kubelet configuration of each master from its
+// resources, the masters but the first one get it before they join.
+func (p *Provider) EnsureKubeletConfiguration(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureKubeletConfiguration)
+}
+
+// EnsureCNI installs flannel, at the version and with the values of the flannel addon
+// of the cluster if it lists one.
+func (p *Provider) EnsureCNI(ctx context.Context, c *typesv1.Cluster) error {
+	applier, err := apiclient.NewApplier(c.TargetConfig, "")
+	if err != nil {
+		return err
+	}
+	spec := platformv1.ClusterAddon{Name: addons.Flannel}
+	if one := clusterAddon(c, addons.Flannel); one != nil {
+		spec = *one
+	}
+	a, err := addons.Get(spec.Name)
+	if err != nil {
+		return err
+	}
+	version, err := a.Version(spec.Version)
+	if err != nil {
+		return err
+	}
+
+	return a.Install(ctx, applier, a.Option(version, spec.Values, clusterValues(c)))
+}
+
+// EnsureJoinControlPlane joins the masters but the first one to the control plane.
+// The control plane certificates are uploaded again with a fresh key each time, as
+// the key expires along with the uploaded certificates.
+func (p *Provider) EnsureJoinControlPlane(ctx context.Context, c *typesv1.Cluster) error {
+	masters := c.TargetCluster.Spec.Masters
+	if len(masters) == 1 {
+		return nil
+	}
+	completeCluster(c)
+	client, err := kubernetes.NewForConfig(c.TargetConfig)
+	if err != nil {
+		return err
+	}
+
+	certificateKey, err := copycerts.CreateCertificateKey()
+	if err != nil {
+		return err
+	}
+	c.ClusterCredential.CertificateKey = &certificateKey
+	if err := p.initPhase(c, "upload-certs --upload-certs"); err != nil {
+		return err
+	}
+
+	for i := range masters[1:] {
+		master := &masters[i+1]
+		if node, err := apiclient.GetNodeByMachineIP(ctx, client, master.IP); err == nil {
+			if _, ok := node.Labels[constants.LabelNodeRoleMaster]; ok {
+				continue
+			}
+		}
+		s, err := master.SSH()
+		if err != nil {
+			return err
+		}
+		if err := baremetalmachine.CompleteCredential(c, baremetalmachine.TokenDescription(c, master.IP)); err != nil {
+			return err
+		}
+		config, err := p.getKubeadmJoinControlPlaneConfig(c, master)
+		if err != nil {
+			return err
+		}
+		for _, phase := range []string{"preflight", "control-plane-prepare all", "kubelet-start", "control-plane-join all"} {
+			if err := kubeadm.Join(s, config, c.K8sVersionsWithV, phase, masterEndpoints(c)); err != nil {
+				return errors.Wrapf(err, "master %s", master.IP)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) EnsureMarkNode(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureMarkNode)
+}
+
+func (p *Provider) EnsureNodeReady(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, p.machine.EnsureNodeReady)
+}
+
+// EnsureBootstrapTokensRemoved deletes the bootstrap tokens the masters joined with.
+func (p *Provider) EnsureBootstrapTokensRemoved(ctx context.Context, c *typesv1.Cluster) error {
+	client, err := kubernetes.NewForConfig(c.TargetConfig)
+	if err != nil {
+		return err
+	}
+	for i := range c.TargetCluster.Spec.Masters {
+		if err := kubeadm.DeleteBootstrapTokens(client, baremetalmachine.TokenDescription(c, c.TargetCluster.Spec.Masters[i].IP)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// onMasters runs the machine handler on each master of the cluster.
+func (p *Provider) onMasters(ctx context.Context, c *typesv1.Cluster, handler machineprovider.Handler) error {
+	completeCluster(c)
+	for i := range c.TargetCluster.Spec.Masters {
+		master := &c.TargetCluster.Spec.Masters[i]
+		machine := masterMachine(master)
+		machine.Spec.Registries = c.TargetCluster.Spec.Registries
+		if err := handler(ctx, machine, c); err != nil {
+			return errors.Wrapf(err, "master %s", master.IP)
+		}
+	}
+
+	return nil
+}
+
+// initPhase runs the kubeadm init phase on the first master.
+func (p *Provider) initPhase(c *typesv1.Cluster, phase string) error {
+	completeCluster(c)
+	s, err := c.TargetCluster.Spec.Masters[0].SSH()
+	if err != nil {
+		return err
+	}
+
+	return kubeadm.Init(s, p.getKubeadmInitConfig(c), phase)
+}
+
+// completeCluster fills the fields of the cluster wrapper the machine handlers rely on,
+// they are taken from the cluster which is being created instead of a running one.
+func completeCluster(c *typesv1.Cluster) {
+	c.MasterIp = c.TargetCluster.Spec.Masters[0].IP
+	if c.TargetCluster.Spec.HA != nil {
+		c.MasterIp = c.TargetCluster.Spec.HA.VIP
+	}
+	c.K8sVersionsWithV = "v" + strings.TrimPrefix(c.TargetCluster.Spec.Version, "v")
+	if c.ClusterCredential == nil {
+		c.ClusterCredential = &typesv1.ClusterCredential{ClusterName: c.ClusterName}
+	}
+}
+
+// masterEndpoints returns the api server address of the cluster followed by the
+// masters, joining falls back to a master when the vip is not reachable.
+func masterEndpoints(c *typesv1.Cluster) []string {
+	endpoints := []string{c.MasterIp}
+	for _, master := range c.TargetCluster.Spec.Masters {
+		if master.IP != c.MasterIp {
+			endpoints = append(endpoints, master.IP)
+		}
+	}
+
+	return endpoints
+}
+
+func clusterCIDR(c *typesv1.Cluster) string {
+	if c.TargetCluster.Spec.ClusterCIDR != "" {
+		return c.TargetCluster.Spec.ClusterCIDR
+	}
+	return constants.DefaultClusterCIDR
+}
+
+func serviceCIDR(c *typesv1.Cluster) string {
+	if c.TargetCluster.Spec.ServiceCIDR != "" {
+		return c.TargetCluster.Spec.ServiceCIDR
+	}
+	return constants.DefaultServiceCIDR
+}
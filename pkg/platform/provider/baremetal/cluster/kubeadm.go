@@ -0,0 +1,112 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	kubeadmv1beta2 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
+	kubeletv1beta1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubelet/config/v1beta1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeproxy"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+)
+
+func (p *Provider) getKubeadmInitConfig(c *typesv1.Cluster) *kubeadm.InitConfig {
+	master := c.TargetCluster.Spec.Masters[0]
+
+	certSANs := []string{constants.APIServerHostName}
+	for _, one := range c.TargetCluster.Spec.Masters {
+		certSANs = append(certSANs, one.IP)
+	}
+	if c.TargetCluster.Spec.HA != nil {
+		certSANs = append(certSANs, c.TargetCluster.Spec.HA.VIP)
+	}
+
+	initConfiguration := &kubeadmv1beta2.InitConfiguration{
+		NodeRegistration: kubeadmv1beta2.NodeRegistrationOptions{
+			KubeletExtraArgs: p.getKubeletExtraArgs(master.IP),
+		},
+		LocalAPIEndpoint: kubeadmv1beta2.APIEndpoint{
+			AdvertiseAddress: master.IP,
+			BindPort:         constants.APIServerPort,
+		},
+	}
+	if c.ClusterCredential.CertificateKey != nil {
+		initConfiguration.CertificateKey = *c.ClusterCredential.CertificateKey
+	}
+
+	return &kubeadm.InitConfig{
+		InitConfiguration: initConfiguration,
+		ClusterConfiguration: &kubeadmv1beta2.ClusterConfiguration{
+			Networking: kubeadmv1beta2.Networking{
+				ServiceSubnet: serviceCIDR(c),
+				PodSubnet:     clusterCIDR(c),
+				DNSDomain:     constants.DNSDomain,
+			},
+			KubernetesVersion:    c.K8sVersionsWithV,
+			ControlPlaneEndpoint: fmt.Sprintf("%s:%d", constants.APIServerHostName, constants.APIServerPort),
+			APIServer: kubeadmv1beta2.APIServer{
+				CertSANs: certSANs,
+			},
+			ClusterName: c.ClusterName,
+		},
+		// docker runs with the systemd cgroup driver.
+		KubeletConfiguration: &kubeletv1beta1.KubeletConfiguration{
+			CgroupDriver: "systemd",
+		},
+		KubeProxyConfiguration: kubeproxy.Configuration(c.TargetCluster.Spec.KubeProxy),
+	}
+}
+
+func (p *Provider) getKubeadmJoinControlPlaneConfig(c *typesv1.Cluster, master *platformv1.ClusterMachine) (*kubeadmv1beta2.JoinConfiguration, error) {
+	caCertHashes, err := kubeadm.CACertHashes(c.ClusterCredential.CACert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubeadmv1beta2.JoinConfiguration{
+		NodeRegistration: kubeadmv1beta2.NodeRegistrationOptions{
+			KubeletExtraArgs: p.getKubeletExtraArgs(master.IP),
+		},
+		Discovery: kubeadmv1beta2.Discovery{
+			BootstrapToken: &kubeadmv1beta2.BootstrapTokenDiscovery{
+				Token:        *c.ClusterCredential.BootstrapToken,
+				CACertHashes: caCertHashes,
+			},
+			TLSBootstrapToken: *c.ClusterCredential.BootstrapToken,
+		},
+		ControlPlane: &kubeadmv1beta2.JoinControlPlane{
+			LocalAPIEndpoint: kubeadmv1beta2.APIEndpoint{
+				AdvertiseAddress: master.IP,
+				BindPort:         constants.APIServerPort,
+			},
+			CertificateKey: *c.ClusterCredential.CertificateKey,
+		},
+	}, nil
+}
+
+func (p *Provider) getKubeletExtraArgs(machineIP string) map[string]string {
+	return map[string]string{
+		"node-ip":     machineIP,
+		"node-labels": fmt.Sprintf("%s=%s", constants.LabelMachineIPV4, machineIP),
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cluster
+
+import (
+	"net"
+
+	"github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	baremetalmachine "pml.io/april/pkg/platform/provider/baremetal/machine"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeproxy"
+	clusterprovider "pml.io/april/pkg/platform/provider/cluster"
+	importedcluster "pml.io/april/pkg/platform/provider/imported/cluster"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/log"
+)
+
+const (
+	name = "Baremetal"
+)
+
+func init() {
+	p, err := NewProvider()
+	if err != nil {
+		log.Errorf("init cluster provider error: %s", err)
+		return
+	}
+	clusterprovider.Register(p.Name(), p)
+}
+
+// Provider creates a cluster from scratch on the masters of the cluster spec. The
+// masters are prepared by the handlers of the baremetal machine provider, and the
+// new cluster is registered the way an imported one is.
+type Provider struct {
+	*clusterprovider.DelegateProvider
+
+	machine  *baremetalmachine.Provider
+	imported *importedcluster.Provider
+}
+
+var _ clusterprovider.Provider = &Provider{}
+
+func NewProvider() (*Provider, error) {
+	p := new(Provider)
+
+	var err error
+	p.machine, err = baremetalmachine.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	p.imported, err = importedcluster.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	p.DelegateProvider = &clusterprovider.DelegateProvider{
+		ProviderName: name,
+		ValidateFunc: p.validate,
+
+		CreateHandlers: []clusterprovider.Handler{
+			p.EnsureClusterValidated,
+
+			p.EnsureOSDetected,
+			p.EnsureClean,
+			p.EnsureInitAPIServerHost,
+			p.EnsureKernelModule,
+			p.EnsureSysctl,
+			p.EnsureDisableSwap,
+			p.EnsureManifestDir,
+			p.EnsureFirewall,
+			p.EnsureKeepalived,
+			p.EnsurePreflight,
+
+			p.EnsureRegistries,
+			p.EnsureDocker,
+			p.EnsureKubelet,
+			p.EnsureCNIPlugins,
+			p.EnsureConntrackTools,
+			p.EnsureKubeadm,
+			p.EnsureImages,
+
+			p.EnsureKubeadmInitPreflightPhase,
+			p.EnsureKubeadmInitKubeletStartPhase,
+			p.EnsureKubeadmInitCertsPhase,
+			p.EnsureKubeadmInitKubeConfigPhase,
+			p.EnsureKubeadmInitControlPlanePhase,
+			p.EnsureKubeadmInitEtcdPhase,
+			p.EnsureKubeadmInitWaitControlPlanePhase,
+			p.EnsureKubeadmInitUploadConfigPhase,
+			p.EnsureKubeadmInitMarkControlPlanePhase,
+			p.EnsureKubeadmInitBootstrapTokenPhase,
+			p.EnsureKubeadmInitAddonPhase,
+
+			p.EnsureKubeconfigExported,
+			p.EnsureKubeletConfiguration,
+			p.EnsureCNI,
+			p.EnsureJoinControlPlane,
+			p.EnsureMarkNode,
+			p.EnsureNodeReady,
+			p.EnsureBootstrapTokensRemoved,
+			p.EnsureAddons,
+
+			p.imported.EnsureClusterCapability,
+			p.imported.EnsureVKInstalled,
+			p.imported.EnsureVKReady,
+			p.imported.EnsureTargetRegistered,
+		},
+		UpgradeHandlers: []clusterprovider.Handler{
+			p.EnsureUpgradePreflight,
+			p.EnsureImages,
+			p.EnsureCNIPlugins,
+			p.EnsureConntrackTools,
+			p.EnsureKubeadm,
+			p.EnsureKubeadmUpgrade,
+			p.EnsureKubeletUpgraded,
+			p.EnsureKubeletConfiguration,
+			p.EnsureNodeReady,
+		},
+		UpdateHandlers: []clusterprovider.Handler{
+			p.imported.EnsureVKInstalled,
+			p.imported.EnsureClusterCapability,
+			p.EnsureCertsRenewed,
+			p.EnsureAddons,
+			p.EnsureGPUAddonRemoved,
+		},
+		DeleteHandlers: []clusterprovider.Handler{
+			p.imported.EnsureTargetRemoved,
+			p.EnsureFirewallReverted,
+		},
+	}
+
+	return p, nil
+}
+
+func (p *Provider) validate(cluster *typesv1.Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+
+	spec := cluster.TargetCluster.Spec
+	specPath := field.NewPath("spec")
+	if len(spec.Masters) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("masters"), "at least one master is required"))
+	}
+	for i, master := range spec.Masters {
+		if master.IP == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("masters").Index(i).Child("ip"), ""))
+		}
+	}
+	if spec.HA != nil {
+		haPath := specPath.Child("ha")
+		if net.ParseIP(spec.HA.VIP) == nil {
+			allErrs = append(allErrs, field.Invalid(haPath.Child("vip"), spec.HA.VIP, "must be a valid IP address"))
+		}
+		for i, master := range spec.Masters {
+			if master.IP == spec.HA.VIP {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("masters").Index(i).Child("ip"), master.IP,
+					"must differ from the vip"))
+			}
+		}
+	}
+	if spec.Version == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("version"), ""))
+	}
+	if spec.KubeProxy != nil {
+		proxyPath := specPath.Child("kubeProxy")
+		switch spec.KubeProxy.Mode {
+		case "", kubeproxy.ModeIPTables:
+			if spec.KubeProxy.IPVSScheduler != "" {
+				allErrs = append(allErrs, field.Invalid(proxyPath.Child("ipvsScheduler"), spec.KubeProxy.IPVSScheduler,
+					"only applies to the ipvs mode"))
+			}
+		case kubeproxy.ModeIPVS:
+			if spec.KubeProxy.IPVSScheduler != "" && !funk.ContainsString(kubeproxy.IPVSSchedulers, spec.KubeProxy.IPVSScheduler) {
+				allErrs = append(allErrs, field.NotSupported(proxyPath.Child("ipvsScheduler"), spec.KubeProxy.IPVSScheduler,
+					kubeproxy.IPVSSchedulers))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(proxyPath.Child("mode"), spec.KubeProxy.Mode,
+				[]string{kubeproxy.ModeIPTables, kubeproxy.ModeIPVS}))
+		}
+	}
+	allErrs = append(allErrs, validateAddons(spec.Addons, specPath.Child("addons"))...)
+	if spec.KubeconfigSecret == nil || spec.KubeconfigSecret.Name == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("kubeconfigSecret", "name"),
+			"the kubeconfig of the new cluster is exported to this config map"))
+	}
+
+	return allErrs
+}
+
+// masterMachine wraps a master of the cluster into a Machine, so that it can be
+// handed to the handlers of the baremetal machine provider.
+func masterMachine(master *platformv1.ClusterMachine) *platformv1.Machine {
+	return &platformv1.Machine{
+		Spec: platformv1.MachineSpec{
+			IP:         master.IP,
+			Port:       master.Port,
+			Username:   master.Username,
+			Password:   master.Password,
+			PrivateKey: master.PrivateKey,
+			PassPhrase: master.PassPhrase,
+			Labels:     master.Labels,
+			Taints:     master.Taints,
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	baremetalmachine "pml.io/april/pkg/platform/provider/baremetal/machine"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubelet"
+	"pml.io/april/pkg/platform/provider/baremetal/preflight"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+)
+
+// EnsureUpgradePreflight checks the cluster is upgraded one minor at most, and that the
+// store has the packages of the release of the new version for every master. The
+// handlers of the upgrade run with the version of the spec, so the packages they
+// install are the ones of the new release.
+func (p *Provider) EnsureUpgradePreflight(ctx context.Context, c *typesv1.Cluster) error {
+	if err := checkUpgradeVersion(c.TargetCluster.Status.Version, c.TargetCluster.Spec.Version); err != nil {
+		return err
+	}
+
+	return p.onMasters(ctx, c, func(ctx context.Context, machine *platformv1.Machine, c *typesv1.Cluster) error {
+		machineSSH, err := machine.Spec.SSH()
+		if err != nil {
+			return err
+		}
+		release, err := baremetalmachine.KubernetesRelease(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		return preflight.RunUpgradeChecks(machineSSH, c.K8sVersionsWithV, release)
+	})
+}
+
+// EnsureKubeadmUpgrade upgrades the control plane of the masters one at a time.
+func (p *Provider) EnsureKubeadmUpgrade(ctx context.Context, c *typesv1.Cluster) error {
+	completeCluster(c)
+	client, err := kubernetes.NewForConfig(c.TargetConfig)
+	if err != nil {
+		return err
+	}
+	for i := range c.TargetCluster.Spec.Masters {
+		master := &c.TargetCluster.Spec.Masters[i]
+		s, err := master.SSH()
+		if err != nil {
+			return err
+		}
+		if err := kubeadm.Upgrade(s, client, c.K8sVersionsWithV, i == 0); err != nil {
+			return errors.Wrapf(err, "master %s", master.IP)
+		}
+	}
+
+	return nil
+}
+
+// EnsureKubeletUpgraded replaces the kubelet of the masters, the kubelet is started
+// again whether the new one could be installed or not.
+func (p *Provider) EnsureKubeletUpgraded(ctx context.Context, c *typesv1.Cluster) error {
+	return p.onMasters(ctx, c, func(ctx context.Context, machine *platformv1.Machine, c *typesv1.Cluster) error {
+		s, err := machine.Spec.SSH()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = kubelet.ServiceOperate(s, kubelet.Start) }()
+		if err := kubelet.ServiceOperate(s, kubelet.Stop); err != nil {
+			return err
+		}
+
+		return p.machine.EnsureKubelet(ctx, machine, c)
+	})
+}
+
+// checkUpgradeVersion refuses to downgrade and to skip a minor, which kubeadm doesn't support.
+func checkUpgradeVersion(from, to string) error {
+	current, err := semver.NewVersion(strings.TrimPrefix(from, "v"))
+	if err != nil {
+		return fmt.Errorf("invalid version %q of the cluster: %w", from, err)
+	}
+	desired, err := semver.NewVersion(strings.TrimPrefix(to, "v"))
+	if err != nil {
+		return fmt.Errorf("invalid version %q to upgrade to: %w", to, err)
+	}
+	if desired.LessThan(current) {
+		return fmt.Errorf("can't downgrade the cluster from %s to %s", from, to)
+	}
+	if desired.Major() != current.Major() || desired.Minor() > current.Minor()+1 {
+		return fmt.Errorf("can't upgrade the cluster from %s to %s, upgrade one minor at a time", from, to)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckUpgradeVersion(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  bool
+	}{
+		{"1.21.5", "1.21.9", false},
+		{"v1.21.5", "1.22.2", false},
+		{"1.21.5", "1.23.0", true},
+		{"1.22.2", "1.21.5", true},
+		{"1.21.5", "2.0.0", true},
+		{"", "1.22.2", true},
+	}
+	for _, tt := range tests {
+		err := checkUpgradeVersion(tt.from, tt.to)
+		if tt.wantErr {
+			assert.Error(t, err, "%s to %s", tt.from, tt.to)
+		} else {
+			assert.NoError(t, err, "%s to %s", tt.from, tt.to)
+		}
+	}
+}
@@ -21,6 +21,7 @@ const (
 	KubernetesAuthzWebhookConfigFile    = KubernetesDir + AuthzWebhookConfigName
 	KubeadmConfigFileName               = KubernetesDir + "kubeadm-config.yaml"
 	KubeletKubeConfigFileName           = KubernetesDir + "kubelet.conf"
+	AdminKubeConfigFileName             = KubernetesDir + "admin.conf"
 
 	KubeletPodManifestDir                = KubernetesDir + "manifests/"
 	EtcdPodManifestFile                  = KubeletPodManifestDir + "etcd.yaml"
@@ -85,17 +86,20 @@ const (
 	LabelNodeRoleMaster = "node-role.kubernetes.io/master"
 
 	// Provider
-	ProviderDir           = "provider/baremetal/"
-	SrcDir                = ProviderDir + "res/"
-	ConfDir               = ProviderDir + "conf/"
-	ConfigFile            = ConfDir + "config.yaml"
-	AuditPolicyConfigFile = ConfDir + AuditPolicyConfigName
-	OIDCConfigFile        = ConfDir + OIDCCACertName
-	ManifestsDir          = ProviderDir + "manifests/"
-	GPUManagerManifest    = ManifestsDir + "gpu-manager/gpu-manager.yaml"
-	CSIOperatorManifest   = ManifestsDir + "csi-operator/csi-operator.yaml"
-	MetricsServerManifest = ManifestsDir + "metrics-server/metrics-server.yaml"
-	CiliumManifest        = ManifestsDir + "cilium/cilium.yaml"
+	ProviderDir                = "provider/baremetal/"
+	SrcDir                     = ProviderDir + "res/"
+	ConfDir                    = ProviderDir + "conf/"
+	ConfigFile                 = ConfDir + "config.yaml"
+	AuditPolicyConfigFile      = ConfDir + AuditPolicyConfigName
+	OIDCConfigFile             = ConfDir + OIDCCACertName
+	ManifestsDir               = ProviderDir + "manifests/"
+	NvidiaDevicePluginManifest = ManifestsDir + "gpu/nvidia-device-plugin.yaml"
+	GPUManagerManifest         = ManifestsDir + "gpu-manager/gpu-manager.yaml"
+	GPUQuotaAdmissionManifest  = ManifestsDir + "gpu-manager/gpu-quota-admission.yaml"
+	CSIOperatorManifest        = ManifestsDir + "csi-operator/csi-operator.yaml"
+	MetricsServerManifest      = ManifestsDir + "metrics-server/metrics-server.yaml"
+	CiliumManifest             = ManifestsDir + "cilium/cilium.yaml"
+	FlannelManifest            = ManifestsDir + "flannel/flannel.yaml"
 
 	KUBERNETES               = 1
 	DNSIPIndex               = 10
@@ -104,13 +108,29 @@ const (
 
 	// RenewCertsTimeThreshold control how long time left to renew certs
 	RenewCertsTimeThreshold = 30 * 24 * time.Hour
+	// CertsCheckInterval control how often the certs of the masters are checked
+	CertsCheckInterval = 12 * time.Hour
 
 	// MinNumCPU mininum cpu number.
 	MinNumCPU = 2
 
 	APIServerHostName = "pml.io"
+	// APIServerPort is the port kube-apiserver listens on in the clusters created by april.
+	APIServerPort = 6443
+
+	// Defaults of the networking of the clusters created by april.
+	DefaultClusterCIDR = "10.244.0.0/16"
+	DefaultServiceCIDR = "10.96.0.0/12"
+	DNSDomain          = "cluster.local"
 
 	NeedUpgradeCoreDNSK8sVersion = "1.19.0"
 
 	LabelMachineIPV4 = "pml.io/machine-ip"
+
+	// CompatibilityConfigMap overrides the compatibility matrix of the store, it lives in
+	// the namespace of the cluster configs.
+	CompatibilityConfigMap = "april-compatibility"
+	// GPUAddonConfigMap configures the addon exposing the GPUs of the clusters to the
+	// pods, it lives in the namespace of the cluster configs.
+	GPUAddonConfigMap = "april-gpu-addon"
 )
@@ -57,3 +57,15 @@ const (
 	// May be overridden by a flag at startup.
 	KubeSchedulerPort = 10259
 )
+
+const (
+	// FlannelVXLANPort is the udp port of the vxlan backend of flannel.
+	FlannelVXLANPort = 8472
+	// FlannelUDPPort is the udp port of the udp backend of flannel.
+	FlannelUDPPort = 8285
+	// FlannelWireGuardPort is the udp port of the wireguard backend of flannel.
+	FlannelWireGuardPort = 51820
+	// NodePortRangeStart and NodePortRangeEnd is the default service node port range.
+	NodePortRangeStart = 30000
+	NodePortRangeEnd   = 32767
+)
@@ -60,8 +60,8 @@ var components = Components{
 	ETCD:               containerregistry.Image{Name: "etcd", Tag: "v3.4.7"},
 	CoreDNS:            containerregistry.Image{Name: "coredns", Tag: "1.7.0"},
 	Pause:              containerregistry.Image{Name: "pause", Tag: "3.2"},
-	NvidiaDevicePlugin: containerregistry.Image{Name: "nvidia-device-plugin", Tag: "1.0.0-beta4"},
-	Keepalived:         containerregistry.Image{Name: "keepalived", Tag: "2.0.16-r0"},
+	NvidiaDevicePlugin: containerregistry.Image{Name: "nvidia-device-plugin", Tag: "v0.9.0"},
+	Keepalived:         containerregistry.Image{Name: "keepalived", Tag: "2.0.20"},
 
 	GPUManager:        containerregistry.Image{Name: "gpu-manager", Tag: "v1.0.6"},
 	Busybox:           containerregistry.Image{Name: "busybox", Tag: "1.31.1"},
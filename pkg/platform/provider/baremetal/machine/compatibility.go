@@ -0,0 +1,47 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/res"
+	importedconstants "pml.io/april/pkg/platform/provider/imported/constants"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/ssh"
+)
+
+// KubernetesRelease returns the packages going with the version of the cluster, the
+// releases of the compatibility ConfigMap override the ones of the store.
+func KubernetesRelease(ctx context.Context, cluster *typesv1.Cluster) (*res.Release, error) {
+	compatibility, err := res.StoreCompatibility()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := cluster.MasterKubeclientset.CoreV1().ConfigMaps(importedconstants.ClusterConfigNamespace).Get(ctx, constants.CompatibilityConfigMap, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get compatibility matrix error: %w", err)
+	}
+	if err == nil {
+		overrides, err := res.ParseCompatibility([]byte(cm.Data[res.CompatibilityFile]))
+		if err != nil {
+			return nil, fmt.Errorf("configmap %s: %w", constants.CompatibilityConfigMap, err)
+		}
+		compatibility.Merge(overrides)
+	}
+
+	return compatibility.ReleaseOf(cluster.K8sVersionsWithV)
+}
+
+// packageVersion returns the version of the package to install on the machine, the one
+// going with the version of the cluster.
+func packageVersion(ctx context.Context, p *res.Package, s ssh.Interface, cluster *typesv1.Cluster) (string, error) {
+	release, err := KubernetesRelease(ctx, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	return release.Version(p, res.Arch(s))
+}
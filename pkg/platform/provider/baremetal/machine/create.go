@@ -4,31 +4,39 @@ import (
 	//multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
-	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
-	api "k8s.io/kubernetes/pkg/apis/core"
+	"net"
 
 	"os"
 	"path"
 	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	kubeproxyv1alpha1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeproxy/config/v1alpha1"
 	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/addons"
 	"pml.io/april/pkg/platform/provider/baremetal/phases/addons/cniplugins"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/distro"
 	"pml.io/april/pkg/platform/provider/baremetal/phases/docker"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/firewall"
 	"pml.io/april/pkg/platform/provider/baremetal/phases/gpu"
 	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
-	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeconfig"
 	"pml.io/april/pkg/platform/provider/baremetal/phases/kubelet"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeproxy"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/registry"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/storage"
 	"pml.io/april/pkg/platform/provider/baremetal/preflight"
 	"pml.io/april/pkg/platform/provider/baremetal/res"
 	typesv1 "pml.io/april/pkg/platform/provider/type"
 	"pml.io/april/pkg/util/apiclient"
 	"pml.io/april/pkg/util/cmdstring"
 	"pml.io/april/pkg/util/hosts"
+	"pml.io/april/pkg/util/log"
+	"pml.io/april/pkg/util/ssh"
 	"strings"
 	"time"
 )
@@ -36,7 +44,6 @@ import (
 const (
 	sysctlFile       = "/etc/sysctl.conf"
 	sysctlCustomFile = "/etc/sysctl.d/99-tke.conf"
-	moduleFile       = "/etc/modules-load.d/tke.conf"
 )
 
 func (p *Provider) EnsureCopyFiles(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
@@ -75,13 +82,48 @@ func (p *Provider) EnsureClean(ctx context.Context, machine *platformv1.Machine,
 	return nil
 }
 
+// osProfile connects to the machine, and detects the distribution it runs.
+func osProfile(machine *platformv1.Machine) (ssh.Interface, distro.Profile, error) {
+	machineSSH, err := machine.Spec.SSH()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile, err := distro.Detect(machineSSH)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return machineSSH, profile, nil
+}
+
+func (p *Provider) EnsureOSDetected(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	_, profile, err := osProfile(machine)
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("os detected", "machine", machine.Name, "distribution", profile.Name())
+
+	return nil
+}
+
 func (p *Provider) EnsurePreflight(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
 	machineSSH, err := machine.Spec.SSH()
 	if err != nil {
 		return err
 	}
 
-	err = preflight.RunNodeChecks(cluster, machineSSH)
+	proxy, err := kubeProxyConfiguration(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	release, err := KubernetesRelease(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	err = preflight.RunNodeChecks(cluster, machineSSH, kubeproxy.KernelModules(proxy), release)
 	if err != nil {
 		return err
 	}
@@ -115,12 +157,18 @@ func (p *Provider) EnsureRegistryHosts(ctx context.Context, machine *platformv1.
 }
 
 func (p *Provider) EnsureKernelModule(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	s, err := machine.Spec.SSH()
+	s, profile, err := osProfile(machine)
+	if err != nil {
+		return err
+	}
+
+	proxy, err := kubeProxyConfiguration(ctx, cluster)
 	if err != nil {
 		return err
 	}
 
-	modules := []string{"iptable_nat", "ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh"}
+	// only the modules of the proxy mode of the cluster.
+	modules := kubeproxy.KernelModules(proxy)
 	if _, err := s.CombinedOutput("modinfo br_netfilter"); err == nil {
 		modules = append(modules, "br_netfilter")
 	}
@@ -132,7 +180,7 @@ func (p *Provider) EnsureKernelModule(ctx context.Context, machine *platformv1.M
 		}
 		data.WriteString(m + "\n")
 	}
-	err = s.WriteFile(strings.NewReader(data.String()), moduleFile)
+	err = s.WriteFile(strings.NewReader(data.String()), profile.ModulesLoadFile())
 	if err != nil {
 		return err
 	}
@@ -140,6 +188,20 @@ func (p *Provider) EnsureKernelModule(ctx context.Context, machine *platformv1.M
 	return nil
 }
 
+// kubeProxyConfiguration returns the configuration of kube-proxy on the machine, a
+// cluster being created has it in its spec, a running one in its ConfigMap.
+func kubeProxyConfiguration(ctx context.Context, cluster *typesv1.Cluster) (*kubeproxyv1alpha1.KubeProxyConfiguration, error) {
+	if cluster.TargetCluster != nil {
+		return kubeproxy.Configuration(cluster.TargetCluster.Spec.KubeProxy), nil
+	}
+
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return nil, err
+	}
+	return kubeproxy.Get(ctx, client)
+}
+
 func (p *Provider) EnsureSysctl(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
 	machineSSH, err := machine.Spec.SSH()
 	if err != nil {
@@ -172,19 +234,88 @@ func (p *Provider) EnsureSysctl(ctx context.Context, machine *platformv1.Machine
 }
 
 func (p *Provider) EnsureDisableSwap(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	machineSSH, profile, err := osProfile(machine)
+	if err != nil {
+		return err
+	}
+
+	_, err = machineSSH.CombinedOutput(profile.DisableSwap())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Provider) EnsureFirewall(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
 	machineSSH, err := machine.Spec.SSH()
 	if err != nil {
 		return err
 	}
+	rules, err := CNIRules(cluster)
+	if err != nil {
+		return err
+	}
+
+	return firewall.Open(machineSSH, append(firewall.NodeRules(), rules...))
+}
+
+// CNIRules are the ports the CNI addon of the cluster serves on every node, the CNI is
+// flannel with its defaults unless the cluster lists it with other values. A machine
+// joining a running cluster has no spec of the cluster, it gets the defaults.
+func CNIRules(cluster *typesv1.Cluster) ([]firewall.Rule, error) {
+	a, err := addons.Get(addons.Flannel)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]string
+	if cluster.TargetCluster != nil {
+		for _, one := range cluster.TargetCluster.Spec.Addons {
+			if one.Name == a.Name {
+				values = one.Values
+			}
+		}
+	}
+
+	var rules []firewall.Rule
+	for _, port := range a.NodePorts(values) {
+		rules = append(rules, firewall.Rule{Protocol: port.Protocol, Port: port.Port})
+	}
+
+	return rules, nil
+}
 
-	_, err = machineSSH.CombinedOutput(`swapoff -a && sed -i "s/^[^#]*swap/#&/" /etc/fstab`)
+// EnsureFirewallReverted removes the rules april added to the firewall of the machine.
+// A machine which is gone must not block the deletion, one which is reachable is
+// retried until its firewall is reverted.
+func (p *Provider) EnsureFirewallReverted(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	machineSSH, err := machine.Spec.SSH()
 	if err != nil {
 		return err
 	}
+	// the config is only dialed by a command, the machine is probed with the short dial
+	// timeout of its config.
+	if err := machineSSH.Ping(); err != nil {
+		if unreachable(err) {
+			log.FromContext(ctx).Info("Can't reach the machine, leave its firewall alone", "machine", machine.Spec.IP, "error", err)
+			return nil
+		}
+		return fmt.Errorf("revert the firewall of machine %s error: %w", machine.Spec.IP, err)
+	}
+	if err := firewall.Revert(machineSSH); err != nil {
+		return fmt.Errorf("revert the firewall of machine %s error: %w", machine.Spec.IP, err)
+	}
 
 	return nil
 }
 
+// unreachable tells the error is one of dialing the machine or of a connection timing
+// out, rather than one returned by the machine.
+func unreachable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
 func (p *Provider) EnsureDisableOffloading(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
 	machineSSH, err := machine.Spec.SSH()
 	if err != nil {
@@ -212,43 +343,38 @@ func (p *Provider) EnsureManifestDir(ctx context.Context, machine *platformv1.Ma
 	return nil
 }
 
-func (p *Provider) EnsureKubeconfig(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	masterEndpoint := fmt.Sprintf("https://%s:%d", cluster.MasterIp, 6443)
+// EnsureNvidiaDriver installs the driver of the GPUs, and records them in the status of
+// the machine.
+func (p *Provider) EnsureNvidiaDriver(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !gpu.IsEnable(machine.Spec) {
+		return nil
+	}
+	option := &gpu.NvidiaDriverOption{}
+	if machine.Spec.GPU != nil {
+		if vendor := machine.Spec.GPU.Vendor; vendor != "" && vendor != platformv1.GPUVendorNvidia {
+			return fmt.Errorf("unsupported GPU vendor %q", vendor)
+		}
+		option.Version = machine.Spec.GPU.DriverVersion
+	}
 
-	machineSSH, err := machine.Spec.SSH()
+	machineSSH, profile, err := osProfile(machine)
 	if err != nil {
 		return err
 	}
-
-	option := &kubeconfig.Option{
-		MasterEndpoint: masterEndpoint,
-		ClusterName:    cluster.ClusterName,
-		CACert:         cluster.ClusterCredential.CACert,
-		Token:          *cluster.ClusterCredential.Token,
+	if !gpu.MachineIsSupport(machineSSH) {
+		return fmt.Errorf("no NVIDIA GPU is found on the machine")
 	}
-	err = kubeconfig.Install(machineSSH, option)
-	if err != nil {
+	option.InstallKernelHeaders = profile.InstallPackages(profile.KernelHeadersPackage())
+	if err := gpu.InstallNvidiaDriver(machineSSH, option); err != nil {
 		return err
 	}
 
-	return nil
+	machine.Status.GPU, err = gpu.Inventory(machineSSH)
+	return err
 }
 
-//func (p *Provider) EnsureNvidiaDriver(ctx context.Context, machine *platformv1.Machine, cluster string) error {
-//	if !gpu.IsEnable(machine.Spec.Labels) {
-//		return nil
-//	}
-//
-//	machineSSH, err := machine.Spec.SSH()
-//	if err != nil {
-//		return err
-//	}
-//
-//	return gpu.InstallNvidiaDriver(machineSSH, &gpu.NvidiaDriverOption{})
-//}
-
 func (p *Provider) EnsureNvidiaContainerRuntime(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	if !gpu.IsEnable(machine.Spec.Labels) {
+	if !gpu.IsEnable(machine.Spec) {
 		return nil
 	}
 
@@ -260,39 +386,26 @@ func (p *Provider) EnsureNvidiaContainerRuntime(ctx context.Context, machine *pl
 	return gpu.InstallNvidiaContainerRuntime(machineSSH, &gpu.NvidiaContainerRuntimeOption{})
 }
 
-func (p *Provider) EnsureNvidiaDevicePlugin(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	option := &gpu.NvidiaDevicePluginOption{
-		Image: "nvcr.io/nvidia/k8s-device-plugin:v0.9.0",
-	}
-
-	targetClientset, err := kubernetes.NewForConfig(cluster.TargetConfig)
-	if err != nil {
-		return err
-	}
-
-	err = gpu.InstallNvidiaDevicePlugin(ctx, targetClientset, option)
+func (p *Provider) EnsureDocker(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	machineSSH, profile, err := osProfile(machine)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (p *Provider) EnsureDocker(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	machineSSH, err := machine.Spec.SSH()
+	version, err := packageVersion(ctx, &res.Docker, machineSSH, cluster)
 	if err != nil {
 		return err
 	}
+	insecure, mirrors := registry.DockerOptions(machine.Spec.Registries)
 
-	insecureRegistries := fmt.Sprintf(`"%s"`, "p.config.Registry.Domain")
-
-	//extraArgs := cluster.Spec.DockerExtraArgs
-	//utilruntime.Must(mergo.Merge(&extraArgs, p.config.Docker.ExtraArgs))
 	option := &docker.Option{
-		InsecureRegistries: insecureRegistries,
-		RegistryDomain:     insecureRegistries,
-		IsGPU:              gpu.IsEnable(machine.Spec.Labels),
+		InsecureRegistries: quote(insecure),
+		RegistryMirrors:    quote(mirrors),
+		IsGPU:              gpu.IsEnable(machine.Spec),
 		ExtraArgs:          nil, // TODO
+		EnvironmentFile:    profile.EnvironmentFile("docker"),
+		Firewall:           profile.Firewall(),
+		Version:            version,
 	}
 	err = docker.Install(machineSSH, option)
 	if err != nil {
@@ -323,7 +436,11 @@ func (p *Provider) EnsureCNIPlugins(ctx context.Context, machine *platformv1.Mac
 		return err
 	}
 
-	option := &cniplugins.Option{}
+	version, err := packageVersion(ctx, &res.CNIPlugins, machineSSH, cluster)
+	if err != nil {
+		return err
+	}
+	option := &cniplugins.Option{Version: version}
 	err = cniplugins.Install(machineSSH, option)
 	if err != nil {
 		return err
@@ -332,12 +449,23 @@ func (p *Provider) EnsureCNIPlugins(ctx context.Context, machine *platformv1.Mac
 }
 
 func (p *Provider) EnsureConntrackTools(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	machineSSH, err := machine.Spec.SSH()
+	machineSSH, profile, err := osProfile(machine)
 	if err != nil {
 		return err
 	}
 
-	err = res.ConntrackTools.InstallWithDefault(machineSSH)
+	// prefer the package of the distribution, the tarball is for offline nodes.
+	_, err = machineSSH.CombinedOutput(profile.InstallPackages(profile.ConntrackPackage()))
+	if err == nil {
+		return nil
+	}
+	log.FromContext(ctx).Info("install conntrack from package manager failed, fallback to tarball", "machine", machine.Name, "error", err)
+
+	version, err := packageVersion(ctx, &res.ConntrackTools, machineSSH, cluster)
+	if err != nil {
+		return err
+	}
+	err = res.ConntrackTools.Install(machineSSH, version)
 	if err != nil {
 		return err
 	}
@@ -346,12 +474,12 @@ func (p *Provider) EnsureConntrackTools(ctx context.Context, machine *platformv1
 }
 
 func (p *Provider) EnsureKubeadm(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
-	machineSSH, err := machine.Spec.SSH()
+	machineSSH, profile, err := osProfile(machine)
 	if err != nil {
 		return err
 	}
 
-	err = kubeadm.Install(machineSSH, cluster.K8sVersionsWithV)
+	err = kubeadm.Install(machineSSH, cluster.K8sVersionsWithV, profile.EnvironmentFile("kubelet"))
 	if err != nil {
 		return err
 	}
@@ -365,10 +493,14 @@ func (p *Provider) EnsureJoinPhasePreflight(ctx context.Context, machine *platfo
 		return err
 	}
 	// make sure bootstrap token exist.
-	if err := completeCredential(cluster); err != nil {
+	if err := CompleteCredential(cluster, TokenDescription(cluster, machine.Spec.IP)); err != nil {
+		return err
+	}
+	config, err := p.getKubeadmJoinConfig(cluster, machine.Spec.IP)
+	if err != nil {
 		return err
 	}
-	err = kubeadm.Join(machineSSH, p.getKubeadmJoinConfig(cluster, machine.Spec.IP), "preflight", []string{cluster.MasterIp})
+	err = kubeadm.Join(machineSSH, config, cluster.K8sVersionsWithV, "preflight", p.getMasterEndpoints(ctx, cluster))
 	if err != nil {
 		return err
 	}
@@ -382,16 +514,43 @@ func (p *Provider) EnsureJoinPhaseKubeletStart(ctx context.Context, machine *pla
 		return err
 	}
 	// make sure bootstrap token exist.
-	if err := completeCredential(cluster); err != nil {
+	if err := CompleteCredential(cluster, TokenDescription(cluster, machine.Spec.IP)); err != nil {
+		return err
+	}
+	config, err := p.getKubeadmJoinConfig(cluster, machine.Spec.IP)
+	if err != nil {
 		return err
 	}
-	err = kubeadm.Join(machineSSH, p.getKubeadmJoinConfig(cluster, machine.Spec.IP), "kubelet-start", []string{cluster.MasterIp})
+	err = kubeadm.Join(machineSSH, config, cluster.K8sVersionsWithV, "kubelet-start", p.getMasterEndpoints(ctx, cluster))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// getMasterEndpoints returns the api server address of the cluster followed by the
+// masters of it, so that joining falls back to another master when one is down.
+func (p *Provider) getMasterEndpoints(ctx context.Context, cluster *typesv1.Cluster) []string {
+	endpoints := []string{cluster.MasterIp}
+	clientset, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return endpoints
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: constants.LabelNodeRoleMaster})
+	if err != nil {
+		return endpoints
+	}
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP && address.Address != cluster.MasterIp {
+				endpoints = append(endpoints, address.Address)
+			}
+		}
+	}
+
+	return endpoints
+}
+
 func (p *Provider) EnsureMarkNode(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
 	clientset, err := kubernetes.NewForConfig(cluster.TargetConfig)
 	if err != nil {
@@ -402,13 +561,40 @@ func (p *Provider) EnsureMarkNode(ctx context.Context, machine *platformv1.Machi
 	if err != nil {
 		return err
 	}
-	err = apiclient.MarkNode(ctx, clientset, node.Name, machine.Spec.Labels, machine.Spec.Taints)
+	labels := make(map[string]string)
+	for k, v := range gpu.NodeLabels(machine.Status.GPU) {
+		labels[k] = v
+	}
+	for k, v := range storage.NodeLabels(machine.Spec) {
+		labels[k] = v
+	}
+	for k, v := range machine.Spec.Labels {
+		labels[k] = v
+	}
+	taints := machine.Spec.Taints
+	for _, one := range storage.NodeTaints(machine.Spec) {
+		if !taintExists(taints, one.Key) {
+			taints = append(taints, one)
+		}
+	}
+	err = apiclient.MarkNode(ctx, clientset, node.Name, labels, taints)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// taintExists tells whether one of the taints has the key.
+func taintExists(taints []corev1.Taint, key string) bool {
+	for _, one := range taints {
+		if one.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p *Provider) EnsureNodeReady(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
 	clientset, err := kubernetes.NewForConfig(cluster.TargetConfig)
 	if err != nil {
@@ -440,24 +626,66 @@ func (p *Provider) EnsureInitAPIServerHost(ctx context.Context, machine *platfor
 	return remoteHosts.Set(cluster.MasterIp)
 }
 
-func completeCredential(cluster *typesv1.Cluster) error {
+// CompleteCredential makes sure the cluster credential carries the CA cert of the
+// cluster and a valid bootstrap token carrying the description.
+func CompleteCredential(cluster *typesv1.Cluster, description string) error {
 	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
 	if err != nil {
 		return err
 	}
-	// 1. first check if bootstrap token type secret exist and valid.
-	secrets, err := client.CoreV1().Secrets(api.NamespaceSystem).List(context.Background(), metav1.ListOptions{LabelSelector: labels.Everything().String()})
-	for _, secret := range secrets.Items {
-		if secret.Type == bootstrapapi.SecretTypeBootstrapToken {
-			// check secret validation
-			if tempTokenStr, ok := kubeadm.ValidateSecretForSigning(&secret); ok {
-				cluster.ClusterCredential.BootstrapToken = &tempTokenStr
-				return nil
-			}
+	if len(cluster.ClusterCredential.CACert) == 0 {
+		cluster.ClusterCredential.CACert, err = clusterCACert(cluster)
+		if err != nil {
+			return err
 		}
 	}
-	// 2. no valid bootstrap token, then create one!
-	tempTokenStr, err := kubeadm.CreateShortLivedBootstrapToken(client)
-	cluster.ClusterCredential.BootstrapToken = &tempTokenStr
+	tokenStr, err := kubeadm.EnsureBootstrapToken(client, description)
+	if err != nil {
+		return err
+	}
+	cluster.ClusterCredential.BootstrapToken = &tokenStr
+
 	return nil
 }
+
+// clusterCACert returns the CA certificate of the cluster, from its kubeconfig or else
+// from the first master. A machine created by the machine controller has no spec of the
+// cluster, so no master to read it from.
+func clusterCACert(cluster *typesv1.Cluster) ([]byte, error) {
+	if len(cluster.TargetConfig.CAData) > 0 {
+		return cluster.TargetConfig.CAData, nil
+	}
+	if cluster.TargetConfig.CAFile != "" {
+		return ioutil.ReadFile(cluster.TargetConfig.CAFile)
+	}
+	if cluster.TargetCluster == nil || len(cluster.TargetCluster.Spec.Masters) == 0 {
+		return nil, fmt.Errorf("no CA certificate of cluster %s to join it with", cluster.ClusterName)
+	}
+	s, err := cluster.TargetCluster.Spec.Masters[0].SSH()
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.ReadFile(constants.CACertName)
+	if err != nil {
+		return nil, fmt.Errorf("read %s of master %s error: %w", constants.CACertName, cluster.TargetCluster.Spec.Masters[0].IP, err)
+	}
+
+	return data, nil
+}
+
+// TokenDescription points the bootstrap token of a machine at the machine by its IP,
+// the masters of a cluster have no Machine of their own.
+func TokenDescription(cluster *typesv1.Cluster, machineIP string) string {
+	return fmt.Sprintf("bootstrap token of machine %s of Cluster %s", machineIP, cluster.ClusterName)
+}
+
+// EnsureBootstrapTokenRemoved deletes the bootstrap token of the machine, the node
+// doesn't need it anymore once it is ready.
+func (p *Provider) EnsureBootstrapTokenRemoved(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+
+	return kubeadm.DeleteBootstrapTokens(client, TokenDescription(cluster, machine.Spec.IP))
+}
@@ -0,0 +1,49 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/addons"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/firewall"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+)
+
+func TestCNIRules(t *testing.T) {
+	// a machine created by the machine controller has no spec of the cluster.
+	rules, err := CNIRules(&typesv1.Cluster{})
+	assert.NoError(t, err)
+	assert.Equal(t, []firewall.Rule{{Protocol: "udp", Port: constants.FlannelVXLANPort}}, rules)
+
+	cluster := &typesv1.Cluster{TargetCluster: &platformv1.Cluster{}}
+	cluster.TargetCluster.Spec.Addons = []platformv1.ClusterAddon{
+		{Name: addons.Flannel, Values: map[string]string{"Backend": "host-gw"}},
+	}
+	rules, err = CNIRules(cluster)
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestClusterCACert(t *testing.T) {
+	cluster := &typesv1.Cluster{ClusterName: "demo", TargetConfig: &rest.Config{}}
+	_, err := clusterCACert(cluster)
+	assert.Error(t, err)
+
+	cluster.TargetConfig.CAData = []byte("ca")
+	data, err := clusterCACert(cluster)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ca"), data)
+}
+
+func TestUnreachable(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	assert.True(t, unreachable(dial))
+	assert.True(t, unreachable(fmt.Errorf("exec cmd %q eror: %w", "pwd", dial)))
+	assert.False(t, unreachable(errors.New("ssh: unable to authenticate")))
+}
@@ -0,0 +1,124 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/gpu"
+	importedconstants "pml.io/april/pkg/platform/provider/imported/constants"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/apiclient"
+)
+
+// gpuAddonOption returns the GPU addon configured by the ConfigMap of the addon, the
+// default addon if there is none.
+func gpuAddonOption(ctx context.Context, cluster *typesv1.Cluster) (*gpu.AddonOption, error) {
+	var data map[string]string
+	cm, err := cluster.MasterKubeclientset.CoreV1().ConfigMaps(importedconstants.ClusterConfigNamespace).Get(ctx, constants.GPUAddonConfigMap, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get gpu addon config error: %w", err)
+	}
+	if err == nil {
+		data = cm.Data
+	}
+	option, err := gpu.ParseAddonOption(data)
+	if err != nil {
+		return nil, fmt.Errorf("configmap %s: %w", constants.GPUAddonConfigMap, err)
+	}
+
+	return option, nil
+}
+
+// EnsureGPUAddon installs the GPU addon in the cluster when a machine with GPUs joins
+// it, the addon is installed once and updated when its configuration changes.
+func (p *Provider) EnsureGPUAddon(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !gpu.IsEnable(machine.Spec) || machine.Status.GPU == nil {
+		return nil
+	}
+	option, err := gpuAddonOption(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+	applier, err := apiclient.NewApplier(cluster.TargetConfig, "")
+	if err != nil {
+		return err
+	}
+
+	return gpu.EnsureAddon(ctx, client, applier, option)
+}
+
+// EnsureGPUAddonRemoved removes the GPU addon from the cluster when the last node with
+// GPUs leaves it.
+func (p *Provider) EnsureGPUAddonRemoved(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !gpu.IsEnable(machine.Spec) {
+		return nil
+	}
+
+	return RemoveUnusedGPUAddon(ctx, cluster, machine.Spec.IP)
+}
+
+// RemoveUnusedGPUAddon removes the GPU addon from the cluster unless a node with GPUs
+// remains in it. The nodes being deleted, the ones of the given machine IPs and the
+// ones of the machines being deleted are not counted, so that machines deleted at the
+// same time do not keep the addon of each other.
+func RemoveUnusedGPUAddon(ctx context.Context, cluster *typesv1.Cluster, leavingIPs ...string) error {
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+	installed, err := gpu.InstalledAddon(ctx, client)
+	if err != nil || installed == nil {
+		return err
+	}
+	leaving, err := leavingMachineIPs(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	leaving.Insert(leavingIPs...)
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: gpu.LabelGPUVendor})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes.Items {
+		if node.DeletionTimestamp != nil || leaving.Has(node.Name) || leaving.Has(node.Labels[string(apiclient.LabelMachineIPV4)]) {
+			continue
+		}
+		return nil
+	}
+
+	applier, err := apiclient.NewApplier(cluster.TargetConfig, "")
+	if err != nil {
+		return err
+	}
+
+	return gpu.RemoveAddon(ctx, client, applier)
+}
+
+// leavingMachineIPs returns the IPs of the machines of the cluster being deleted.
+func leavingMachineIPs(ctx context.Context, cluster *typesv1.Cluster) (sets.String, error) {
+	ips := sets.NewString()
+	if cluster.MasterPlatformClientset == nil {
+		return ips, nil
+	}
+	machines, err := cluster.MasterPlatformClientset.PlatformV1alpha1().Machines().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list machines error: %w", err)
+	}
+	for _, machine := range machines.Items {
+		if machine.Spec.ClusterName == cluster.ClusterName && machine.DeletionTimestamp != nil {
+			ips.Insert(machine.Spec.IP)
+		}
+	}
+
+	return ips, nil
+}
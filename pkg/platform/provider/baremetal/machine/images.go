@@ -0,0 +1,95 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/docker"
+	"pml.io/april/pkg/platform/provider/baremetal/res"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/log"
+)
+
+const (
+	// kubeadmConfigMap is the ConfigMap in kube-system kubeadm uploads its configuration to.
+	kubeadmConfigMap        = "kubeadm-config"
+	clusterConfigurationKey = "ClusterConfiguration"
+)
+
+// EnsureImages prepares the images of the kubernetes version before the node joins,
+// they are loaded from the image tarball of the store when there is one, and pulled
+// otherwise. The result of every image is recorded in the status of the machine.
+func (p *Provider) EnsureImages(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	machineSSH, err := machine.Spec.SSH()
+	if err != nil {
+		return err
+	}
+
+	repository, err := imageRepository(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	images, err := docker.KubeadmImages(machineSSH, cluster.K8sVersionsWithV, repository)
+	if err != nil {
+		return err
+	}
+
+	if _, err := res.KubernetesImages.ResourceForNode(machineSSH, cluster.K8sVersionsWithV); err == nil {
+		dstFile, err := res.KubernetesImages.CopyToNode(machineSSH, cluster.K8sVersionsWithV)
+		if err != nil {
+			return err
+		}
+		err = docker.LoadImages(machineSSH, dstFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.FromContext(ctx).Info("No image tarball in the store, pull the images", "version", cluster.K8sVersionsWithV, "error", err)
+	}
+
+	var failed []string
+	machine.Status.Images = nil
+	for _, image := range images {
+		status := platformv1.ImageStatus{Name: image, Ready: true}
+		if err := docker.EnsureImage(machineSSH, image); err != nil {
+			status.Ready = false
+			status.Message = err.Error()
+			failed = append(failed, image)
+		}
+		machine.Status.Images = append(machine.Status.Images, status)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("prepare images %s error", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// imageRepository returns the image repository in the kubeadm configuration of the
+// cluster. A cluster april is creating has none yet, its kubeadm uses the default one.
+func imageRepository(ctx context.Context, cluster *typesv1.Cluster) (string, error) {
+	if cluster.TargetConfig == nil {
+		return "", nil
+	}
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return "", err
+	}
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, kubeadmConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("get the kubeadm configuration of the cluster error: %w", err)
+	}
+	config := struct {
+		ImageRepository string `yaml:"imageRepository"`
+	}{}
+	if err := yaml.Unmarshal([]byte(cm.Data[clusterConfigurationKey]), &config); err != nil {
+		return "", fmt.Errorf("parse %s of configmap %s error: %w", clusterConfigurationKey, kubeadmConfigMap, err)
+	}
+
+	return config.ImageRepository, nil
+}
@@ -22,11 +22,16 @@ import (
 	"fmt"
 	kubeadmv1beta2 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
 	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
 	typesv1 "pml.io/april/pkg/platform/provider/type"
 )
 
-func (p *Provider) getKubeadmJoinConfig(c *typesv1.Cluster, machineIP string) *kubeadmv1beta2.JoinConfiguration {
+func (p *Provider) getKubeadmJoinConfig(c *typesv1.Cluster, machineIP string) (*kubeadmv1beta2.JoinConfiguration, error) {
 	apiServerEndpoint := c.MasterIp
+	caCertHashes, err := kubeadm.CACertHashes(c.ClusterCredential.CACert)
+	if err != nil {
+		return nil, err
+	}
 
 	nodeRegistration := kubeadmv1beta2.NodeRegistrationOptions{}
 	kubeletExtraArgs := p.getKubeletExtraArgs(c)
@@ -46,13 +51,13 @@ func (p *Provider) getKubeadmJoinConfig(c *typesv1.Cluster, machineIP string) *k
 		NodeRegistration: nodeRegistration,
 		Discovery: kubeadmv1beta2.Discovery{
 			BootstrapToken: &kubeadmv1beta2.BootstrapTokenDiscovery{
-				Token:                    *c.ClusterCredential.BootstrapToken,
-				APIServerEndpoint:        apiServerEndpoint,
-				UnsafeSkipCAVerification: true,
+				Token:             *c.ClusterCredential.BootstrapToken,
+				APIServerEndpoint: apiServerEndpoint,
+				CACertHashes:      caCertHashes,
 			},
 			TLSBootstrapToken: *c.ClusterCredential.BootstrapToken,
 		},
-	}
+	}, nil
 }
 
 func (p *Provider) getKubeletExtraArgs(c *typesv1.Cluster) map[string]string {
@@ -0,0 +1,170 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeconfig"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/apiclient"
+	"pml.io/april/pkg/util/log"
+)
+
+const (
+	// nodeReaderClusterRole is all a machine may do with its kubeconfig.
+	nodeReaderClusterRole = "april:node-reader"
+)
+
+// nodeKubeconfigAllowed is the policy on which machines get a kubeconfig at all. The
+// owners of personal and anonymous machines are not trusted with any access to the
+// cluster their machines join.
+func nodeKubeconfigAllowed(machine *platformv1.Machine) bool {
+	return machine.Spec.ProviderType == platformv1.TypeEnterprise
+}
+
+// nodeServiceAccountName is the service account the kubeconfig of the machine carries
+// the token of, the same name is used for its token secret and cluster role binding.
+func nodeServiceAccountName(machine *platformv1.Machine) string {
+	return fmt.Sprintf("april-node-%s", machine.Name)
+}
+
+// EnsureKubeconfig installs a kubeconfig on the machine when the policy allows it. It
+// can only read nodes, and is revoked when the machine is deleted.
+func (p *Provider) EnsureKubeconfig(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !nodeKubeconfigAllowed(machine) {
+		return nil
+	}
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+	token, err := ensureNodeToken(ctx, client, machine)
+	if err != nil {
+		return err
+	}
+
+	machineSSH, err := machine.Spec.SSH()
+	if err != nil {
+		return err
+	}
+	option := &kubeconfig.Option{
+		MasterEndpoint: fmt.Sprintf("https://%s:%d", cluster.MasterIp, constants.APIServerPort),
+		ClusterName:    cluster.ClusterName,
+		CACert:         cluster.ClusterCredential.CACert,
+		User:           nodeServiceAccountName(machine),
+		Token:          token,
+	}
+
+	return kubeconfig.Install(machineSSH, option)
+}
+
+// EnsureKubeconfigRevoked deletes the service account of the machine along with its
+// token, the kubeconfig on the machine is removed too if the machine is reachable.
+func (p *Provider) EnsureKubeconfigRevoked(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+	name := nodeServiceAccountName(machine)
+	err = client.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	err = client.CoreV1().Secrets(metav1.NamespaceSystem).Delete(ctx, name+"-token", metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	err = client.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	// the token is useless already, a machine which is gone must not block the deletion.
+	machineSSH, err := machine.Spec.SSH()
+	if err == nil {
+		err = kubeconfig.Uninstall(machineSSH)
+	}
+	if err != nil {
+		log.FromContext(ctx).Info("Can't remove the kubeconfig from the machine", "machine", machine.Name, "error", err)
+	}
+
+	return nil
+}
+
+// ensureNodeToken returns the token of the service account of the machine, which is
+// bound to nodeReaderClusterRole.
+func ensureNodeToken(ctx context.Context, client kubernetes.Interface, machine *platformv1.Machine) (string, error) {
+	name := nodeServiceAccountName(machine)
+	err := apiclient.CreateOrUpdateClusterRole(ctx, client, &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeReaderClusterRole},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{""},
+			Resources: []string{"nodes"},
+			Verbs:     []string{"get", "list", "watch"},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	err = apiclient.CreateOrUpdateServiceAccount(ctx, client, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceSystem},
+	})
+	if err != nil {
+		return "", err
+	}
+	err = apiclient.CreateOrUpdateClusterRoleBinding(ctx, client, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     nodeReaderClusterRole,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// the token controller fills the token into the secret, which must not be updated
+	// by us afterwards.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name + "-token",
+			Namespace:   metav1.NamespaceSystem,
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: name},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	_, err = client.CoreV1().Secrets(secret.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+
+	var token string
+	err = wait.PollImmediate(time.Second, time.Minute, func() (bool, error) {
+		secret, err := client.CoreV1().Secrets(secret.Namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		token = string(secret.Data[corev1.ServiceAccountTokenKey])
+		return token != "", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("wait for the token of service account %s error: %w", name, err)
+	}
+
+	return token, nil
+}
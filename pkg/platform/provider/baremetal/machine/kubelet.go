@@ -0,0 +1,104 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubelet"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/ssh"
+)
+
+// EnsureKubeletConfiguration derives the kubelet configuration of the machine from its
+// resources and the one of the cluster. It runs before the join, so that kubelet starts
+// with it, and on updates to follow the changes of either.
+func (p *Provider) EnsureKubeletConfiguration(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	machineSSH, profile, err := osProfile(machine)
+	if err != nil {
+		return err
+	}
+	base, err := clusterKubeletConfig(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	option := &kubelet.ConfigOption{
+		CPUCores: machine.Spec.CpuCore,
+		MemoryMB: machine.Spec.MemSize,
+	}
+	// the declared resources are a promise of the seller, fall back to the node.
+	if option.CPUCores <= 0 || option.MemoryMB <= 0 {
+		option.CPUCores, option.MemoryMB, err = nodeResources(machineSSH)
+		if err != nil {
+			return err
+		}
+	}
+	option.CgroupDriver, err = cgroupDriver(machineSSH)
+	if err != nil {
+		return err
+	}
+	if machine.Spec.KubeletConfiguration != nil {
+		option.Overrides = machine.Spec.KubeletConfiguration.Raw
+	}
+
+	return kubelet.Render(machineSSH, base, profile.EnvironmentFile("kubelet"), option)
+}
+
+// clusterKubeletConfig returns the kubelet configuration kubeadm uploaded to the cluster.
+func clusterKubeletConfig(ctx context.Context, cluster *typesv1.Cluster) ([]byte, error) {
+	name, err := kubelet.ConfigMapName(cluster.K8sVersionsWithV)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get the kubelet configuration of the cluster error: %w", err)
+	}
+	data, ok := cm.Data[kubelet.ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("configmap %s has no %s", name, kubelet.ConfigMapKey)
+	}
+
+	return []byte(data), nil
+}
+
+func nodeResources(s ssh.Interface) (cores int, memoryMB int, err error) {
+	out, err := s.CombinedOutput("nproc && awk '/^MemTotal:/{print $2}' /proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected resources of the node %q", string(out))
+	}
+	cores, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	memoryKB, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return cores, memoryKB / 1024, nil
+}
+
+// cgroupDriver returns the cgroup driver of docker, systemd is assumed for the other runtimes.
+func cgroupDriver(s ssh.Interface) (string, error) {
+	if _, err := s.LookPath("docker"); err != nil {
+		return "systemd", nil
+	}
+	out, err := s.CombinedOutput("docker info --format '{{.CgroupDriver}}'")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -37,6 +37,7 @@ func NewProvider() (*Provider, error) {
 			p.EnsureCopyFiles,
 			p.EnsurePreInstallHook,
 
+			p.EnsureOSDetected,
 			p.EnsureClean,
 			//p.EnsureRegistryHosts,
 			p.EnsureInitAPIServerHost,
@@ -45,31 +46,46 @@ func NewProvider() (*Provider, error) {
 			p.EnsureSysctl,
 			p.EnsureDisableSwap,
 			p.EnsureManifestDir,
+			p.EnsureFirewall,
 
 			p.EnsurePreflight, // wait basic setting done
 
-			//p.EnsureNvidiaDriver,
+			p.EnsureStorage,
+			p.EnsureNvidiaDriver,
 			p.EnsureNvidiaContainerRuntime,
+			p.EnsureRegistries,
 			p.EnsureDocker,         // 这是system service
 			p.EnsureKubelet,        // 这个也是system service
 			p.EnsureCNIPlugins,     // 解压一大堆的 二进制
 			p.EnsureConntrackTools, //
 			p.EnsureKubeadm,
+			p.EnsureImages,
 
 			// should we support control node? I don't know.
 
 			p.EnsureJoinPhasePreflight,
+			p.EnsureKubeletConfiguration,
 			p.EnsureJoinPhaseKubeletStart,
 			//
 			p.EnsureKubeconfig,
 			p.EnsureMarkNode,
 			p.EnsureNodeReady,
-			// check if we should create nvidia device plugin.
-			p.EnsureNvidiaDevicePlugin,
+			p.EnsureLocalPersistentVolumes,
+			p.EnsureBootstrapTokenRemoved,
+			p.EnsureGPUAddon,
 			p.EnsureDisableOffloading, // will remove it when upgrade to k8s v1.18.5
 			p.EnsurePostInstallHook,
 		},
-		UpdateHandlers: []machineprovider.Handler{},
+		UpdateHandlers: []machineprovider.Handler{
+			p.EnsureKubeletConfiguration,
+		},
+		DeleteHandlers: []machineprovider.Handler{
+			p.EnsureGPUAddonRemoved,
+			p.EnsureLocalPersistentVolumesRemoved,
+			p.EnsureKubeconfigRevoked,
+			p.EnsureBootstrapTokenRemoved,
+			p.EnsureFirewallReverted,
+		},
 	}
 	//cfg, err := config.New(constants.ConfigFile)
 	//if err != nil {
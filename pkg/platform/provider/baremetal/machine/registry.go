@@ -0,0 +1,60 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/registry"
+	importedconstants "pml.io/april/pkg/platform/provider/imported/constants"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+)
+
+// EnsureRegistries configures the registries of the machine, the credentials are read
+// from the secrets of the registries and given to docker and kubelet, so that pods
+// need no image pull secrets of their own.
+func (p *Provider) EnsureRegistries(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if len(machine.Spec.Registries) == 0 {
+		return nil
+	}
+
+	machineSSH, err := machine.Spec.SSH()
+	if err != nil {
+		return err
+	}
+
+	auths := make(registry.Auths)
+	for _, r := range machine.Spec.Registries {
+		if r.AuthSecret == "" {
+			continue
+		}
+		secret, err := cluster.MasterKubeclientset.CoreV1().Secrets(importedconstants.ClusterConfigNamespace).Get(ctx, r.AuthSecret, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get auth secret of registry %s error: %w", r.Host, err)
+		}
+		if secret.Type != corev1.SecretTypeDockerConfigJson {
+			return fmt.Errorf("auth secret %s of registry %s is not of type %s", r.AuthSecret, r.Host, corev1.SecretTypeDockerConfigJson)
+		}
+		found, err := registry.ParseAuths(secret.Data[corev1.DockerConfigJsonKey], registry.Hosts(r))
+		if err != nil {
+			return fmt.Errorf("auth secret %s: %w", r.AuthSecret, err)
+		}
+		for host, auth := range found {
+			auths[host] = auth
+		}
+	}
+
+	return registry.Install(machineSSH, machine.Spec.Registries, auths)
+}
+
+// quote renders the items as the elements of a json array.
+func quote(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, fmt.Sprintf("%q", item))
+	}
+	return strings.Join(quoted, ", ")
+}
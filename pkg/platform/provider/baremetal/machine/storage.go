@@ -0,0 +1,127 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/storage"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+	"pml.io/april/pkg/util/apiclient"
+)
+
+// EnsureStorage formats and mounts the disks selected for the local persistent volumes
+// of a storage or hybrid machine, and records the free disks of the machine in its
+// status.
+func (p *Provider) EnsureStorage(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !storage.IsEnable(machine.Spec) {
+		return nil
+	}
+	option, err := storage.Option(machine.Spec)
+	if err != nil {
+		return err
+	}
+	machineSSH, profile, err := osProfile(machine)
+	if err != nil {
+		return err
+	}
+	disks, err := storage.Disks(machineSSH)
+	if err != nil {
+		return err
+	}
+
+	selected := make(map[string]bool, len(option.Devices))
+	for _, device := range option.Devices {
+		selected[device] = true
+	}
+	if len(option.Devices) > 0 {
+		if _, err := machineSSH.LookPath("mkfs." + option.FSType); err != nil {
+			cmd := profile.InstallPackages(storage.FSPackage(option.FSType))
+			if _, err := machineSSH.CombinedOutput(cmd); err != nil {
+				return fmt.Errorf("exec %q error: %w", cmd, err)
+			}
+		}
+	}
+
+	status := &platformv1.StorageStatus{}
+	for _, disk := range disks {
+		if !selected[disk.Device] && !disk.Free() {
+			continue
+		}
+		one := platformv1.DiskStatus{
+			Device: disk.Device,
+			Size:   *resource.NewQuantity(disk.Size, resource.BinarySI),
+			FSType: disk.FSType,
+		}
+		if selected[disk.Device] {
+			if err := storage.Provision(machineSSH, disk, option.FSType); err != nil {
+				return err
+			}
+			one.FSType = option.FSType
+			one.MountPath = disk.MountPath()
+			delete(selected, disk.Device)
+		}
+		status.Disks = append(status.Disks, one)
+	}
+	for _, device := range option.Devices {
+		if selected[device] {
+			return fmt.Errorf("disk %s is not found on the machine", device)
+		}
+	}
+	status.Capacity = storage.Capacity(status.Disks)
+	machine.Status.Storage = status
+
+	return nil
+}
+
+// EnsureLocalPersistentVolumes offers the provisioned disks of the machine to the
+// cluster as local persistent volumes.
+func (p *Provider) EnsureLocalPersistentVolumes(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !storage.IsEnable(machine.Spec) || machine.Status.Storage == nil {
+		return nil
+	}
+	option, err := storage.Option(machine.Spec)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+	node, err := apiclient.GetNodeByMachineIP(ctx, client, machine.Spec.IP)
+	if err != nil {
+		return err
+	}
+	if err := storage.EnsureStorageClass(ctx, client, option.StorageClassName); err != nil {
+		return err
+	}
+
+	disks := machine.Status.Storage.Disks
+	for i := range disks {
+		if disks[i].MountPath == "" {
+			continue
+		}
+		disks[i].PersistentVolume, err = storage.EnsurePersistentVolume(ctx, client, machine.Name, node.Name, option.StorageClassName, disks[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnsureLocalPersistentVolumesRemoved deletes the local persistent volumes of the
+// machine, the disks are left as they are.
+func (p *Provider) EnsureLocalPersistentVolumesRemoved(ctx context.Context, machine *platformv1.Machine, cluster *typesv1.Cluster) error {
+	if !storage.IsEnable(machine.Spec) || machine.Status.Storage == nil {
+		return nil
+	}
+	client, err := kubernetes.NewForConfig(cluster.TargetConfig)
+	if err != nil {
+		return err
+	}
+
+	return storage.RemovePersistentVolumes(ctx, client, machine.Name)
+}
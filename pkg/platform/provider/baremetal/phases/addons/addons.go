@@ -0,0 +1,263 @@
+package addons
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/thoas/go-funk"
+	"k8s.io/client-go/kubernetes"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/images"
+	"pml.io/april/pkg/util/apiclient"
+)
+
+// The addons the clusters may install. The GPU addon is not one of them, it goes with
+// the GPU machines of a cluster.
+const (
+	MetricsServer = "metrics-server"
+	Flannel       = "flannel"
+	CSIOperator   = "csi-operator"
+)
+
+// LabelAddon labels the objects of an addon with its name, the objects of the addon
+// which are not in its manifest any more are pruned.
+const LabelAddon = "pml.io/addon"
+
+// Workload is a workload of an addon, the addon is healthy once all of them are ready.
+type Workload struct {
+	// Kind is Deployment or DaemonSet.
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Port is a port an addon serves on every node of the cluster.
+type Port struct {
+	Protocol string
+	Port     int
+}
+
+// Addon is an addon installed with a manifest, the manifest is rendered with the values
+// of the addon along with Image and Version.
+type Addon struct {
+	Name     string
+	Manifest string
+	// Images are the images of the supported versions.
+	Images         map[string]string
+	DefaultVersion string
+	// Values are the defaults of the values of the manifest.
+	Values map[string]string
+	// ValidValues are the supported values of the values which take one of a few.
+	ValidValues map[string][]string
+	// CNI tells the addon is the network plugin of the cluster, which is installed with
+	// the control plane and never uninstalled.
+	CNI       bool
+	Workloads []Workload
+	// Ports returns the ports the addon serves on every node with the values, nil if
+	// it serves none.
+	Ports func(values map[string]string) []Port
+}
+
+var addons = map[string]*Addon{
+	MetricsServer: {
+		Name:     MetricsServer,
+		Manifest: constants.MetricsServerManifest,
+		Images: map[string]string{
+			"v0.3.6": "k8s.gcr.io/metrics-server-amd64:v0.3.6",
+			"v0.3.7": "k8s.gcr.io/metrics-server/metrics-server:v0.3.7",
+		},
+		DefaultVersion: images.Get().MetricsServer.Tag,
+		Values:         map[string]string{"Replicas": "1"},
+		Workloads:      []Workload{{Kind: "Deployment", Namespace: "kube-system", Name: "metrics-server"}},
+	},
+	Flannel: {
+		Name:     Flannel,
+		Manifest: constants.FlannelManifest,
+		Images: map[string]string{
+			"v0.14.0": "quay.io/coreos/flannel:v0.14.0",
+			"v0.15.1": "quay.io/coreos/flannel:v0.15.1",
+		},
+		DefaultVersion: "v0.14.0",
+		Values:         map[string]string{"Backend": "vxlan"},
+		ValidValues:    map[string][]string{"Backend": {"host-gw", "udp", "vxlan", "wireguard"}},
+		CNI:            true,
+		Workloads:      []Workload{{Kind: "DaemonSet", Namespace: "kube-system", Name: "kube-flannel-ds"}},
+		Ports:          flannelPorts,
+	},
+	CSIOperator: {
+		Name:     CSIOperator,
+		Manifest: constants.CSIOperatorManifest,
+		Images: map[string]string{
+			"v1.0.0": "tkestack/csi-operator:v1.0.0",
+		},
+		DefaultVersion: "v1.0.0",
+		Values: map[string]string{
+			"Replicas":       "1",
+			"KubeletRootDir": "/var/lib/kubelet",
+			"RegistryDomain": "docker.io/tkestack",
+		},
+		Workloads: []Workload{{Kind: "Deployment", Namespace: "kube-system", Name: "csi-operator"}},
+	},
+}
+
+// flannelPorts are the ports of the backend of flannel, host-gw routes the traffic
+// without any.
+func flannelPorts(values map[string]string) []Port {
+	switch values["Backend"] {
+	case "vxlan":
+		return []Port{{Protocol: "udp", Port: constants.FlannelVXLANPort}}
+	case "udp":
+		return []Port{{Protocol: "udp", Port: constants.FlannelUDPPort}}
+	case "wireguard":
+		return []Port{{Protocol: "udp", Port: constants.FlannelWireGuardPort}}
+	}
+
+	return nil
+}
+
+// Get returns the addon of the name.
+func Get(name string) (*Addon, error) {
+	a, ok := addons[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown addon %q", name)
+	}
+
+	return a, nil
+}
+
+// Names returns the names of the addons.
+func Names() []string {
+	var names []string
+	for name := range addons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Versions returns the supported versions of the addon.
+func (a *Addon) Versions() []string {
+	var versions []string
+	for version := range a.Images {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+// NodePorts returns the ports the addon serves on every node with the values, which
+// override the defaults of the addon.
+func (a *Addon) NodePorts(values map[string]string) []Port {
+	if a.Ports == nil {
+		return nil
+	}
+	merged := make(map[string]string, len(a.Values)+len(values))
+	for _, m := range []map[string]string{a.Values, values} {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+
+	return a.Ports(merged)
+}
+
+// Version returns the version to install, the default version if version is empty.
+func (a *Addon) Version(version string) (string, error) {
+	if version == "" {
+		return a.DefaultVersion, nil
+	}
+	if _, ok := a.Images[version]; !ok {
+		return "", fmt.Errorf("unsupported version %s of addon %s, supported versions are %v", version, a.Name, a.Versions())
+	}
+
+	return version, nil
+}
+
+// InvalidValues returns the keys of the values which are not among the valid values of
+// the addon, sorted.
+func (a *Addon) InvalidValues(values map[string]string) []string {
+	var keys []string
+	for k, v := range values {
+		valid, ok := a.ValidValues[k]
+		if ok && !funk.ContainsString(valid, v) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Option returns the values the manifest of the version is rendered with, values
+// override the values of the cluster, like ClusterCIDR, which override the defaults
+// of the addon. An Image value overrides the image of the version.
+func (a *Addon) Option(version string, values map[string]string, clusterValues map[string]string) map[string]interface{} {
+	option := make(map[string]interface{})
+	for _, m := range []map[string]string{a.Values, clusterValues, values} {
+		for k, v := range m {
+			option[k] = v
+		}
+	}
+	option["Version"] = version
+	if _, ok := option["Image"]; !ok {
+		option["Image"] = a.Images[version]
+	}
+
+	return option
+}
+
+// Install applies the manifest of the addon, the objects of the addon which are not in
+// the manifest are pruned.
+func (a *Addon) Install(ctx context.Context, applier *apiclient.Applier, option map[string]interface{}) error {
+	data, err := apiclient.RenderFile(a.Manifest, option)
+	if err == nil {
+		err = applier.Apply(ctx, data, apiclient.ApplyOptions{PruneLabels: map[string]string{LabelAddon: a.Name}})
+	}
+	if err != nil {
+		return fmt.Errorf("install addon %s error: %w", a.Name, err)
+	}
+
+	return nil
+}
+
+// Uninstall deletes the objects of the manifest of the addon.
+func (a *Addon) Uninstall(ctx context.Context, applier *apiclient.Applier, option map[string]interface{}) error {
+	data, err := apiclient.RenderFile(a.Manifest, option)
+	if err == nil {
+		err = applier.Delete(ctx, data)
+	}
+	if err != nil {
+		return fmt.Errorf("uninstall addon %s error: %w", a.Name, err)
+	}
+
+	return nil
+}
+
+// Check returns an error telling the first workload of the addon which isn't ready.
+func (a *Addon) Check(ctx context.Context, client kubernetes.Interface) error {
+	for _, w := range a.Workloads {
+		var (
+			ok  bool
+			err error
+		)
+		switch w.Kind {
+		case "Deployment":
+			ok, err = apiclient.CheckDeployment(ctx, client, w.Namespace, w.Name)
+		case "DaemonSet":
+			ok, err = apiclient.CheckDaemonset(ctx, client, w.Namespace, w.Name)
+		default:
+			return fmt.Errorf("unsupported workload kind %s", w.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s/%s is not ready: %w", w.Kind, w.Namespace, w.Name, err)
+		}
+		if !ok {
+			return fmt.Errorf("%s %s/%s is not ready", w.Kind, w.Namespace, w.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package addons
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOption(t *testing.T) {
+	a, err := Get(Flannel)
+	assert.NoError(t, err)
+
+	version, err := a.Version("")
+	assert.NoError(t, err)
+	assert.Equal(t, a.DefaultVersion, version)
+	_, err = a.Version("v0.1.0")
+	assert.Error(t, err)
+
+	option := a.Option("v0.15.1", map[string]string{"Backend": "host-gw"}, map[string]string{"ClusterCIDR": "10.244.0.0/16"})
+	assert.Equal(t, map[string]interface{}{
+		"Backend":     "host-gw",
+		"ClusterCIDR": "10.244.0.0/16",
+		"Image":       "quay.io/coreos/flannel:v0.15.1",
+		"Version":     "v0.15.1",
+	}, option)
+
+	option = a.Option("v0.15.1", map[string]string{"Image": "registry.local/flannel:v0.15.1"}, nil)
+	assert.Equal(t, "registry.local/flannel:v0.15.1", option["Image"])
+
+	_, err = Get("calico")
+	assert.Error(t, err)
+}
+
+func TestInvalidValues(t *testing.T) {
+	a, err := Get(Flannel)
+	assert.NoError(t, err)
+
+	assert.Empty(t, a.InvalidValues(map[string]string{"Backend": "wireguard", "Image": "registry.local/flannel:v0.15.1"}))
+	assert.Equal(t, []string{"Backend"}, a.InvalidValues(map[string]string{"Backend": "ipip"}))
+}
+
+func TestNodePorts(t *testing.T) {
+	a, err := Get(Flannel)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Port{{Protocol: "udp", Port: 8472}}, a.NodePorts(nil))
+	assert.Equal(t, []Port{{Protocol: "udp", Port: 51820}}, a.NodePorts(map[string]string{"Backend": "wireguard"}))
+	assert.Empty(t, a.NodePorts(map[string]string{"Backend": "host-gw"}))
+
+	a, err = Get(MetricsServer)
+	assert.NoError(t, err)
+	assert.Empty(t, a.NodePorts(nil))
+}
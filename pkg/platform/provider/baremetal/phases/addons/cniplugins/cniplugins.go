@@ -8,10 +8,16 @@ import (
 )
 
 type Option struct {
+	// Version of the plugins, the default version if empty.
+	Version string
 }
 
 func Install(s ssh.Interface, option *Option) error {
-	dstFile, err := res.CNIPlugins.CopyToNodeWithDefault(s)
+	version := option.Version
+	if version == "" {
+		version = res.CNIPlugins.DefaultVersion()
+	}
+	dstFile, err := res.CNIPlugins.CopyToNode(s, version)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,169 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package distro hides the differences between the linux distributions the nodes run,
+// so that the same phases prepare a node of any of them.
+package distro
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"strings"
+
+	"pml.io/april/pkg/util/ssh"
+)
+
+const osReleaseFile = "/etc/os-release"
+
+// Profile is what the phases need to know about the distribution of a node.
+type Profile interface {
+	// Name is the family of the distribution.
+	Name() string
+	// EnvironmentFile is the file the systemd unit of the service reads its environment from.
+	EnvironmentFile(service string) string
+	// ModulesLoadFile lists the kernel modules april loads at boot.
+	ModulesLoadFile() string
+	// InstallPackages returns the command installing the packages with the package manager.
+	InstallPackages(packages ...string) string
+	// ConntrackPackage is the package providing the conntrack binary.
+	ConntrackPackage() string
+	// KernelHeadersPackage is the package of the headers of the running kernel, the
+	// kernel modules of drivers are built against them.
+	KernelHeadersPackage() string
+	// Firewall is the service of the firewall shipped with the distribution.
+	Firewall() string
+	// DisableSwap returns the command turning swap off for good.
+	DisableSwap() string
+}
+
+type profile struct {
+	name             string
+	environmentDir   string
+	installPackages  string
+	conntrackPackage string
+	kernelHeaders    string
+	firewall         string
+}
+
+var (
+	// RHEL is CentOS, RHEL and their rebuilds.
+	RHEL Profile = &profile{
+		name:             "rhel",
+		environmentDir:   "/etc/sysconfig",
+		installPackages:  "yum install -y %s",
+		conntrackPackage: "conntrack-tools",
+		kernelHeaders:    "kernel-devel-$(uname -r)",
+		firewall:         "firewalld",
+	}
+	// Debian is Debian and Ubuntu.
+	Debian Profile = &profile{
+		name:           "debian",
+		environmentDir: "/etc/default",
+		// the package index may be missing on a fresh host.
+		installPackages:  "export DEBIAN_FRONTEND=noninteractive; apt-get install -y %[1]s || (apt-get update && apt-get install -y %[1]s)",
+		conntrackPackage: "conntrack",
+		kernelHeaders:    "linux-headers-$(uname -r)",
+		firewall:         "ufw",
+	}
+	OpenEuler Profile = &profile{
+		name:             "openeuler",
+		environmentDir:   "/etc/sysconfig",
+		installPackages:  "dnf install -y %s",
+		conntrackPackage: "conntrack-tools",
+		kernelHeaders:    "kernel-devel-$(uname -r)",
+		firewall:         "firewalld",
+	}
+)
+
+func (p *profile) Name() string {
+	return p.name
+}
+
+func (p *profile) EnvironmentFile(service string) string {
+	return path.Join(p.environmentDir, service)
+}
+
+func (p *profile) ModulesLoadFile() string {
+	return "/etc/modules-load.d/tke.conf"
+}
+
+func (p *profile) InstallPackages(packages ...string) string {
+	return fmt.Sprintf(p.installPackages, strings.Join(packages, " "))
+}
+
+func (p *profile) ConntrackPackage() string {
+	return p.conntrackPackage
+}
+
+func (p *profile) KernelHeadersPackage() string {
+	return p.kernelHeaders
+}
+
+func (p *profile) Firewall() string {
+	return p.firewall
+}
+
+func (p *profile) DisableSwap() string {
+	// ubuntu activates the swap of the fstab through swap.target as well.
+	return `swapoff -a && sed -i "s/^[^#]*swap/#&/" /etc/fstab && (systemctl mask swap.target || true)`
+}
+
+// Detect reads the os-release of the node, and returns the profile of its distribution.
+func Detect(s ssh.Interface) (Profile, error) {
+	data, err := s.ReadFile(osReleaseFile)
+	if err != nil {
+		return nil, fmt.Errorf("read %s error: %w", osReleaseFile, err)
+	}
+
+	return profileOf(parseOSRelease(string(data)))
+}
+
+func profileOf(osRelease map[string]string) (Profile, error) {
+	ids := append([]string{osRelease["ID"]}, strings.Fields(osRelease["ID_LIKE"])...)
+	for _, id := range ids {
+		switch strings.ToLower(id) {
+		case "centos", "rhel", "fedora", "rocky", "almalinux", "ol":
+			return RHEL, nil
+		case "debian", "ubuntu":
+			return Debian, nil
+		case "openeuler":
+			return OpenEuler, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported distribution %q", osRelease["PRETTY_NAME"])
+}
+
+func parseOSRelease(data string) map[string]string {
+	osRelease := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i == -1 {
+			continue
+		}
+		osRelease[line[:i]] = strings.Trim(line[i+1:], `"'`)
+	}
+
+	return osRelease
+}
@@ -0,0 +1,57 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileOf(t *testing.T) {
+	tests := []struct {
+		osRelease string
+		expected  Profile
+	}{
+		{
+			osRelease: "NAME=\"CentOS Linux\"\nVERSION=\"7 (Core)\"\nID=\"centos\"\nID_LIKE=\"rhel fedora\"\n",
+			expected:  RHEL,
+		},
+		{
+			osRelease: "NAME=\"Ubuntu\"\nID=ubuntu\nID_LIKE=debian\nPRETTY_NAME=\"Ubuntu 20.04.2 LTS\"\n",
+			expected:  Debian,
+		},
+		{
+			osRelease: "NAME=\"openEuler\"\nVERSION=\"20.03 (LTS-SP1)\"\nID=\"openEuler\"\n",
+			expected:  OpenEuler,
+		},
+		{
+			osRelease: "NAME=\"Rocky Linux\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n",
+			expected:  RHEL,
+		},
+	}
+	for _, test := range tests {
+		profile, err := profileOf(parseOSRelease(test.osRelease))
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, profile)
+	}
+
+	_, err := profileOf(parseOSRelease("ID=alpine\nPRETTY_NAME=\"Alpine Linux v3.13\"\n"))
+	assert.Error(t, err)
+}
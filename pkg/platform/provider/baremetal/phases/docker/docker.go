@@ -16,10 +16,15 @@ import (
 
 type Option struct {
 	InsecureRegistries string
-	RegistryDomain     string
+	RegistryMirrors    string
 	Options            string
 	IsGPU              bool
 	ExtraArgs          map[string]string
+	// EnvironmentFile holds the extra args, its place depends on the distribution.
+	EnvironmentFile string
+	Firewall        string
+	// Version of docker, the default version if empty.
+	Version string
 }
 
 const (
@@ -28,7 +33,11 @@ const (
 
 func Install(s ssh.Interface, option *Option) error {
 	// 1. copy docker binary source file
-	dstFile, err := res.Docker.CopyToNodeWithDefault(s)
+	version := option.Version
+	if version == "" {
+		version = res.Docker.DefaultVersion()
+	}
+	dstFile, err := res.Docker.CopyToNode(s, version)
 	if err != nil {
 		return err
 	}
@@ -44,7 +53,7 @@ func Install(s ssh.Interface, option *Option) error {
 	for k, v := range option.ExtraArgs {
 		args = append(args, fmt.Sprintf(`--%s="%s"`, k, v))
 	}
-	err = s.WriteFile(strings.NewReader(fmt.Sprintf("DOCKER_EXTRA_ARGS=%s", strings.Join(args, " "))), "/etc/sysconfig/docker")
+	err = s.WriteFile(strings.NewReader(fmt.Sprintf("DOCKER_EXTRA_ARGS=%s", strings.Join(args, " "))), option.EnvironmentFile)
 	if err != nil {
 		return err
 	}
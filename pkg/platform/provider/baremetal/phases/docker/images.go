@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"pml.io/april/pkg/util/ssh"
+)
+
+// LoadImages loads the images saved in the tarball on the node, with ctr when
+// the node runs containerd without docker.
+func LoadImages(s ssh.Interface, file string) error {
+	cmd := fmt.Sprintf("docker load -i %s", file)
+	if _, err := s.LookPath("docker"); err != nil {
+		cmd = fmt.Sprintf("gzip -dcf %s | ctr -n k8s.io images import -", file)
+	}
+	_, err := s.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("load images from %s error: %w", file, err)
+	}
+
+	return nil
+}
+
+// EnsureImage pulls the image unless the node has it already.
+func EnsureImage(s ssh.Interface, image string) error {
+	inspect, pull := "docker image inspect %s", "docker pull %s"
+	if _, err := s.LookPath("docker"); err != nil {
+		inspect, pull = "ctr -n k8s.io images ls -q name==%s | grep -q .", "ctr -n k8s.io images pull %s"
+	}
+	if _, err := s.CombinedOutput(fmt.Sprintf(inspect, image)); err == nil {
+		return nil
+	}
+	_, err := s.CombinedOutput(fmt.Sprintf(pull, image))
+	if err != nil {
+		return fmt.Errorf("pull %s error: %w", image, err)
+	}
+
+	return nil
+}
+
+// KubeadmImages lists the images kubeadm runs the version with, from the repository
+// unless it is empty and the default of kubeadm is used.
+func KubeadmImages(s ssh.Interface, version string, repository string) ([]string, error) {
+	cmd := fmt.Sprintf("kubeadm config images list --kubernetes-version %s", version)
+	if repository != "" {
+		cmd += fmt.Sprintf(" --image-repository %s", repository)
+	}
+	stdout, stderr, exit, err := s.Exec(cmd)
+	if err != nil || exit != 0 {
+		return nil, fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	}
+
+	return strings.Fields(stdout), nil
+}
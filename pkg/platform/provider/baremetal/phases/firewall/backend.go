@@ -0,0 +1,184 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package firewall
+
+import (
+	"fmt"
+	"strings"
+
+	"pml.io/april/pkg/util/ssh"
+)
+
+type firewall interface {
+	name() string
+	// active tells whether the firewall filters the traffic of the node.
+	active(s ssh.Interface) bool
+	allowed(s ssh.Interface, rule Rule) (bool, error)
+	allow(s ssh.Interface, rule Rule) error
+	deny(s ssh.Interface, rule Rule) error
+	// commit makes the changes survive a reboot.
+	commit(s ssh.Interface) error
+}
+
+var firewalls = map[string]firewall{
+	"firewalld": firewalld{},
+	"ufw":       ufw{},
+	"nftables":  nftables{},
+}
+
+// detect returns the active firewall of the node, or nil when there is none.
+func detect(s ssh.Interface) (firewall, error) {
+	for _, name := range []string{"firewalld", "ufw", "nftables"} {
+		if fw := firewalls[name]; fw.active(s) {
+			return fw, nil
+		}
+	}
+	return nil, nil
+}
+
+func run(s ssh.Interface, cmd string, args ...interface{}) error {
+	_, err := s.CombinedOutput(fmt.Sprintf(cmd, args...))
+	return err
+}
+
+type firewalld struct{}
+
+func (firewalld) name() string {
+	return "firewalld"
+}
+
+func (firewalld) active(s ssh.Interface) bool {
+	return run(s, "firewall-cmd --state") == nil
+}
+
+func (firewalld) allowed(s ssh.Interface, rule Rule) (bool, error) {
+	_, _, exit, err := s.Execf("firewall-cmd --permanent --query-port=%s", rule)
+	if err != nil && exit == 0 {
+		return false, err
+	}
+	return exit == 0, nil
+}
+
+func (firewalld) allow(s ssh.Interface, rule Rule) error {
+	return run(s, "firewall-cmd --permanent --add-port=%s", rule)
+}
+
+func (firewalld) deny(s ssh.Interface, rule Rule) error {
+	return run(s, "firewall-cmd --permanent --remove-port=%s", rule)
+}
+
+func (firewalld) commit(s ssh.Interface) error {
+	// the rules are added to the permanent configuration, reload applies them.
+	return run(s, "firewall-cmd --reload")
+}
+
+type ufw struct{}
+
+func (ufw) name() string {
+	return "ufw"
+}
+
+func (ufw) active(s ssh.Interface) bool {
+	out, err := s.CombinedOutput("ufw status")
+	return err == nil && strings.Contains(string(out), "Status: active")
+}
+
+func (ufw) allowed(s ssh.Interface, rule Rule) (bool, error) {
+	out, err := s.CombinedOutput("ufw show added")
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(out), "ufw allow "+ufwRule(rule)+"\n"), nil
+}
+
+func (ufw) allow(s ssh.Interface, rule Rule) error {
+	return run(s, "ufw allow %s", ufwRule(rule))
+}
+
+func (ufw) deny(s ssh.Interface, rule Rule) error {
+	return run(s, "ufw delete allow %s", ufwRule(rule))
+}
+
+func (ufw) commit(s ssh.Interface) error {
+	// ufw saves every rule as it is added.
+	return nil
+}
+
+func ufwRule(rule Rule) string {
+	return rule.ports(":") + "/" + rule.Protocol
+}
+
+// nftables adds the rules to the input chain of the inet filter table, which is
+// the table of the configuration shipped by the distributions.
+type nftables struct{}
+
+const (
+	nftChain   = "inet filter input"
+	nftComment = "april"
+	nftConf    = "/etc/nftables.conf"
+)
+
+func (nftables) name() string {
+	return "nftables"
+}
+
+func (nftables) active(s ssh.Interface) bool {
+	return run(s, "systemctl is-active nftables && nft list chain %s", nftChain) == nil
+}
+
+func (nftables) allowed(s ssh.Interface, rule Rule) (bool, error) {
+	out, err := s.CombinedOutput(fmt.Sprintf("nft list chain %s", nftChain))
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(out), nftMatch(rule)+" accept"), nil
+}
+
+func (nftables) allow(s ssh.Interface, rule Rule) error {
+	return run(s, `nft insert rule %s %s accept comment "%s"`, nftChain, nftMatch(rule), nftComment)
+}
+
+func (nftables) deny(s ssh.Interface, rule Rule) error {
+	out, err := s.CombinedOutput(fmt.Sprintf("nft -a list chain %s", nftChain))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.Contains(line, nftMatch(rule)+" accept") || !strings.Contains(line, fmt.Sprintf(`comment "%s"`, nftComment)) {
+			continue
+		}
+		i := strings.LastIndex(line, "# handle ")
+		if i == -1 {
+			continue
+		}
+		err = run(s, "nft delete rule %s handle %s", nftChain, strings.TrimSpace(line[i+len("# handle "):]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nftables) commit(s ssh.Interface) error {
+	return run(s, `echo "flush ruleset" > %[1]s.april && nft list ruleset >> %[1]s.april && mv -f %[1]s.april %[1]s`, nftConf)
+}
+
+func nftMatch(rule Rule) string {
+	return fmt.Sprintf("%s dport %s", rule.Protocol, rule.ports("-"))
+}
@@ -0,0 +1,196 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package firewall opens the ports kubernetes needs in the firewall active on a node,
+// and reverts what it opened when the node leaves the cluster.
+package firewall
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/util/ssh"
+)
+
+// stateFile records the rules april added, it is outside of /etc/kubernetes which
+// is cleaned when the node is installed again.
+const stateFile = "/var/lib/april/firewall.json"
+
+// Rule allows the traffic to a port, or to the ports from Port to EndPort.
+type Rule struct {
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port"`
+	EndPort  int    `json:"endPort,omitempty"`
+}
+
+func (r Rule) String() string {
+	return r.ports("-") + "/" + r.Protocol
+}
+
+func (r Rule) ports(sep string) string {
+	if r.EndPort == 0 {
+		return strconv.Itoa(r.Port)
+	}
+	return fmt.Sprintf("%d%s%d", r.Port, sep, r.EndPort)
+}
+
+// NodeRules are the ports every node of the cluster serves, the ones of the CNI are
+// up to the CNI addon of the cluster.
+func NodeRules() []Rule {
+	return []Rule{
+		{Protocol: "tcp", Port: constants.KubeletPort},
+		{Protocol: "tcp", Port: constants.ProxyHealthzPort},
+		{Protocol: "tcp", Port: constants.ProxyStatusPort},
+		{Protocol: "tcp", Port: constants.NodePortRangeStart, EndPort: constants.NodePortRangeEnd},
+		{Protocol: "udp", Port: constants.NodePortRangeStart, EndPort: constants.NodePortRangeEnd},
+	}
+}
+
+// ControlPlaneRules are the ports of a master, including the ones of a node.
+func ControlPlaneRules() []Rule {
+	return append(NodeRules(),
+		Rule{Protocol: "tcp", Port: constants.APIServerPort},
+		Rule{Protocol: "tcp", Port: constants.EtcdListenClientPort},
+		Rule{Protocol: "tcp", Port: constants.EtcdListenPeerPort},
+		Rule{Protocol: "tcp", Port: constants.KubeControllerManagerPort},
+		Rule{Protocol: "tcp", Port: constants.KubeSchedulerPort},
+	)
+}
+
+type state struct {
+	Firewall string `json:"firewall"`
+	Rules    []Rule `json:"rules"`
+}
+
+// Open allows the rules in the active firewall of the node, the rules which were
+// allowed before are left alone so that Revert does not close them.
+func Open(s ssh.Interface, rules []Rule) error {
+	fw, err := detect(s)
+	if err != nil || fw == nil {
+		return err
+	}
+
+	st, err := load(s)
+	if err != nil {
+		return err
+	}
+	if st.Firewall != "" && st.Firewall != fw.name() {
+		return fmt.Errorf("rules were added to %s but %s is active now", st.Firewall, fw.name())
+	}
+	st.Firewall = fw.name()
+
+	for _, rule := range rules {
+		if contains(st.Rules, rule) {
+			continue
+		}
+		allowed, err := fw.allowed(s, rule)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			continue
+		}
+		err = fw.allow(s, rule)
+		if err != nil {
+			return fmt.Errorf("allow %s in %s error: %w", rule, fw.name(), err)
+		}
+		st.Rules = append(st.Rules, rule)
+		// save every rule so that a failure later does not lose what was changed.
+		err = save(s, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	return fw.commit(s)
+}
+
+// Revert removes the rules Open added.
+func Revert(s ssh.Interface) error {
+	st, err := load(s)
+	if err != nil {
+		return err
+	}
+	if st.Firewall == "" {
+		return nil
+	}
+	fw, ok := firewalls[st.Firewall]
+	if !ok {
+		return fmt.Errorf("unknown firewall %q in %s", st.Firewall, stateFile)
+	}
+
+	for len(st.Rules) > 0 {
+		rule := st.Rules[len(st.Rules)-1]
+		err = fw.deny(s, rule)
+		if err != nil {
+			return fmt.Errorf("remove %s from %s error: %w", rule, fw.name(), err)
+		}
+		st.Rules = st.Rules[:len(st.Rules)-1]
+		err = save(s, st)
+		if err != nil {
+			return err
+		}
+	}
+	err = fw.commit(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.CombinedOutput(fmt.Sprintf("rm -f %s", stateFile))
+	return err
+}
+
+func contains(rules []Rule, rule Rule) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func load(s ssh.Interface) (*state, error) {
+	st := new(state)
+	if ok, err := s.Exist(stateFile); err != nil || !ok {
+		return st, err
+	}
+	data, err := s.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, st)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s error: %w", stateFile, err)
+	}
+	return st, nil
+}
+
+func save(s ssh.Interface, st *state) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	err = s.WriteFile(bytes.NewReader(data), stateFile)
+	if err != nil {
+		return fmt.Errorf("write %s error: %w", stateFile, err)
+	}
+	return nil
+}
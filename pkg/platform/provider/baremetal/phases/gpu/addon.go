@@ -0,0 +1,193 @@
+package gpu
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/images"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/addons"
+	"pml.io/april/pkg/util/apiclient"
+	"pml.io/april/pkg/util/containerregistry"
+)
+
+// AddonType is the addon exposing the GPUs of a cluster to the pods.
+type AddonType string
+
+const (
+	// AddonNvidiaDevicePlugin hands out whole GPUs.
+	AddonNvidiaDevicePlugin AddonType = "nvidia-device-plugin"
+	// AddonGPUManager shares the GPUs between the pods, the quota admission goes with it.
+	AddonGPUManager AddonType = "gpu-manager"
+
+	// addonName is the value of the addon label of the objects of the addon.
+	addonName = "gpu"
+	// addonStateConfigMap records the addon installed in the target cluster.
+	addonStateConfigMap = "april-gpu-addon"
+
+	addonTypeKey     = "type"
+	addonRegistryKey = "registry"
+)
+
+// defaultRepositories are the public repositories of the images of the addons.
+var defaultRepositories = map[string]string{
+	images.Get().NvidiaDevicePlugin.Name: "nvcr.io/nvidia/k8s-device-plugin",
+	images.Get().GPUManager.Name:         "tkestack/gpu-manager",
+	images.Get().GPUQuotaAdmission.Name:  "tkestack/gpu-quota-admission",
+}
+
+// AddonOption is the addon of a cluster along with its images.
+type AddonOption struct {
+	Type                    AddonType
+	NvidiaDevicePluginImage string
+	GPUManagerImage         string
+	GPUQuotaAdmissionImage  string
+}
+
+// ParseAddonOption returns the addon configured by the data of a ConfigMap, the nvidia
+// device plugin if the type is empty. The images are pulled from the "registry" key if
+// set, else from their public repositories, and an image keyed by the name of its
+// component, e.g. "gpu-manager", is used as is.
+func ParseAddonOption(data map[string]string) (*AddonOption, error) {
+	image := func(component containerregistry.Image) string {
+		if v := data[component.Name]; v != "" {
+			return v
+		}
+		repository := defaultRepositories[component.Name]
+		if registry := data[addonRegistryKey]; registry != "" {
+			repository = strings.TrimSuffix(registry, "/") + "/" + component.Name
+		}
+		return repository + ":" + component.Tag
+	}
+	components := images.Get()
+	option := &AddonOption{
+		Type:                    AddonType(data[addonTypeKey]),
+		NvidiaDevicePluginImage: image(components.NvidiaDevicePlugin),
+		GPUManagerImage:         image(components.GPUManager),
+		GPUQuotaAdmissionImage:  image(components.GPUQuotaAdmission),
+	}
+	switch option.Type {
+	case "":
+		option.Type = AddonNvidiaDevicePlugin
+	case AddonNvidiaDevicePlugin, AddonGPUManager:
+	default:
+		return nil, fmt.Errorf("unknown gpu addon %q", option.Type)
+	}
+
+	return option, nil
+}
+
+func (o *AddonOption) data() map[string]string {
+	components := images.Get()
+	return map[string]string{
+		addonTypeKey:                       string(o.Type),
+		components.NvidiaDevicePlugin.Name: o.NvidiaDevicePluginImage,
+		components.GPUManager.Name:         o.GPUManagerImage,
+		components.GPUQuotaAdmission.Name:  o.GPUQuotaAdmissionImage,
+	}
+}
+
+func (o *AddonOption) manifests() []string {
+	if o.Type == AddonGPUManager {
+		return []string{constants.GPUManagerManifest, constants.GPUQuotaAdmissionManifest}
+	}
+	return []string{constants.NvidiaDevicePluginManifest}
+}
+
+// InstalledAddon returns the addon installed in the cluster, nil if there is none.
+func InstalledAddon(ctx context.Context, client clientset.Interface) (*AddonOption, error) {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, addonStateConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAddonOption(cm.Data)
+}
+
+// EnsureAddon installs the addon in the cluster unless it is installed already, an
+// addon of other images is updated and an addon of another type is replaced, as the
+// objects of the addon which are not applied any more are pruned.
+func EnsureAddon(ctx context.Context, client clientset.Interface, applier *apiclient.Applier, option *AddonOption) error {
+	installed, err := InstalledAddon(ctx, client)
+	if err != nil {
+		return err
+	}
+	if installed != nil && *installed == *option {
+		return nil
+	}
+	// the objects of an addon of another type may miss the addon label, so they are
+	// deleted instead of pruned. The device plugin applied by the machines before the
+	// addon was recorded is such an addon.
+	previous := installed
+	if previous == nil {
+		previous = &AddonOption{Type: AddonNvidiaDevicePlugin, NvidiaDevicePluginImage: option.NvidiaDevicePluginImage}
+	}
+	if previous.Type != option.Type {
+		data, err := previous.render()
+		if err == nil {
+			err = applier.Delete(ctx, data)
+		}
+		if err != nil {
+			return fmt.Errorf("remove gpu addon %s error: %w", previous.Type, err)
+		}
+	}
+	data, err := option.render()
+	if err == nil {
+		err = applier.Apply(ctx, data, apiclient.ApplyOptions{PruneLabels: map[string]string{addons.LabelAddon: addonName}})
+	}
+	if err != nil {
+		return fmt.Errorf("install gpu addon %s error: %w", option.Type, err)
+	}
+
+	return apiclient.CreateOrUpdateConfigMap(ctx, client, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      addonStateConfigMap,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Data: option.data(),
+	})
+}
+
+// RemoveAddon removes the addon installed in the cluster, if any.
+func RemoveAddon(ctx context.Context, client clientset.Interface, applier *apiclient.Applier) error {
+	installed, err := InstalledAddon(ctx, client)
+	if err != nil || installed == nil {
+		return err
+	}
+	data, err := installed.render()
+	if err == nil {
+		err = applier.Delete(ctx, data)
+	}
+	if err != nil {
+		return fmt.Errorf("remove gpu addon %s error: %w", installed.Type, err)
+	}
+	err = client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Delete(ctx, addonStateConfigMap, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// render returns the manifests of the addon rendered into one.
+func (o *AddonOption) render() ([]byte, error) {
+	var docs [][]byte
+	for _, manifest := range o.manifests() {
+		data, err := apiclient.RenderFile(manifest, o)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, data)
+	}
+
+	return bytes.Join(docs, []byte("\n---\n")), nil
+}
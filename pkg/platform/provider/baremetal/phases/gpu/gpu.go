@@ -17,22 +17,61 @@
 package gpu
 
 import (
-	"context"
+	"encoding/csv"
 	"fmt"
-	"pml.io/april/pkg/util/apiclient"
+	"regexp"
+	"strconv"
+	"strings"
 
-	"pml.io/april/pkg/platform/provider/baremetal/res"
-
-	clientset "k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
 	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/res"
 	"pml.io/april/pkg/util/ssh"
 )
 
+const (
+	// legacyEnableLabel enabled the GPUs of a machine before MachineSpec.GPU, it is still honored.
+	legacyEnableLabel = "nvidia-device-enable"
+
+	// The labels of the node describing its GPUs.
+	LabelGPUVendor = "pml.io/gpu-vendor"
+	LabelGPUCount  = "pml.io/gpu-count"
+	LabelGPUModel  = "pml.io/gpu-model"
+	LabelGPUMemory = "pml.io/gpu-memory"
+	LabelGPUDriver = "pml.io/gpu-driver"
+
+	maxLabelValueLen = 63
+)
+
 type NvidiaDriverOption struct {
+	// Version of the driver, the default version if empty.
+	Version string
+	// InstallKernelHeaders is the command installing the headers of the running kernel.
+	InstallKernelHeaders string
 }
 
+// InstallNvidiaDriver installs the driver unless the version is installed already, an
+// installed driver of another version is replaced.
 func InstallNvidiaDriver(s ssh.Interface, option *NvidiaDriverOption) error {
-	dstFile, err := res.NvidiaDriver.CopyToNodeWithDefault(s)
+	version := option.Version
+	if version == "" {
+		version = res.NvidiaDriver.DefaultVersion()
+	}
+	if installed, err := DriverVersion(s); err == nil && installed == version {
+		return nil
+	}
+
+	// the installer builds the kernel module against the headers of the running kernel.
+	if _, err := s.CombinedOutput("test -d /lib/modules/$(uname -r)/build"); err != nil {
+		if option.InstallKernelHeaders == "" {
+			return fmt.Errorf("the headers of the running kernel are missing")
+		}
+		if _, err := s.CombinedOutput(option.InstallKernelHeaders); err != nil {
+			return fmt.Errorf("install the headers of the running kernel error: %w", err)
+		}
+	}
+
+	dstFile, err := res.NvidiaDriver.CopyToNode(s, version)
 	if err != nil {
 		return err
 	}
@@ -49,15 +88,106 @@ func InstallNvidiaDriver(s ssh.Interface, option *NvidiaDriverOption) error {
 		return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
 	}
 
-	cmd = "nvidia-smi"
-	_, stderr, exit, err = s.Exec(cmd)
-	if err != nil || exit != 0 {
-		return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	installed, err := DriverVersion(s)
+	if err != nil {
+		return err
+	}
+	if installed != version {
+		return fmt.Errorf("driver %s is installed instead of %s", installed, version)
 	}
 
 	return nil
 }
 
+// DriverVersion returns the version of the driver installed on the machine.
+func DriverVersion(s ssh.Interface) (string, error) {
+	cmd := "nvidia-smi --query-gpu=driver_version --format=csv,noheader"
+	stdout, stderr, exit, err := s.Exec(cmd)
+	if err != nil || exit != 0 {
+		return "", fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	}
+	lines := strings.Fields(stdout)
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no GPU is reported by nvidia-smi")
+	}
+
+	return lines[0], nil
+}
+
+// Inventory returns the GPUs of the machine reported by nvidia-smi.
+func Inventory(s ssh.Interface) (*platformv1.GPUStatus, error) {
+	cmd := "nvidia-smi --query-gpu=index,uuid,name,memory.total,driver_version --format=csv,noheader,nounits"
+	stdout, stderr, exit, err := s.Exec(cmd)
+	if err != nil || exit != 0 {
+		return nil, fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	}
+
+	return parseInventory(stdout)
+}
+
+func parseInventory(out string) (*platformv1.GPUStatus, error) {
+	r := csv.NewReader(strings.NewReader(out))
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse the output of nvidia-smi error: %w", err)
+	}
+
+	status := &platformv1.GPUStatus{Vendor: platformv1.GPUVendorNvidia}
+	for _, record := range records {
+		if len(record) != 5 {
+			return nil, fmt.Errorf("unexpected line %q of nvidia-smi", strings.Join(record, ", "))
+		}
+		index, err := strconv.ParseInt(record[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q of nvidia-smi: %w", record[0], err)
+		}
+		memory, err := strconv.ParseInt(record[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory %q of nvidia-smi: %w", record[3], err)
+		}
+		status.Devices = append(status.Devices, platformv1.GPUDevice{
+			Index:     int32(index),
+			UUID:      record[1],
+			Model:     record[2],
+			MemoryMiB: memory,
+		})
+		status.DriverVersion = record[4]
+	}
+	status.Count = int32(len(status.Devices))
+
+	return status, nil
+}
+
+// NodeLabels returns the labels describing the GPUs of the inventory, the model and the
+// memory are the ones of the first GPU.
+func NodeLabels(status *platformv1.GPUStatus) map[string]string {
+	if status == nil || status.Count == 0 {
+		return nil
+	}
+	device := status.Devices[0]
+
+	return map[string]string{
+		LabelGPUVendor: labelValue(string(status.Vendor)),
+		LabelGPUCount:  strconv.Itoa(int(status.Count)),
+		LabelGPUModel:  labelValue(device.Model),
+		LabelGPUMemory: strconv.FormatInt(device.MemoryMiB, 10),
+		LabelGPUDriver: labelValue(status.DriverVersion),
+	}
+}
+
+var invalidLabelValueChars = regexp.MustCompile(`[^-A-Za-z0-9_.]+`)
+
+// labelValue makes a valid label value of the string, e.g. Tesla-T4 of "Tesla T4".
+func labelValue(value string) string {
+	value = invalidLabelValueChars.ReplaceAllString(value, "-")
+	if len(value) > maxLabelValueLen {
+		value = value[:maxLabelValueLen]
+	}
+
+	return strings.Trim(value, "-_.")
+}
+
 type NvidiaContainerRuntimeOption struct {
 }
 
@@ -82,24 +212,17 @@ func InstallNvidiaContainerRuntime(s ssh.Interface, option *NvidiaContainerRunti
 	return nil
 }
 
-type NvidiaDevicePluginOption struct {
-	Image string
-}
-
-func InstallNvidiaDevicePlugin(ctx context.Context, clientset clientset.Interface, option *NvidiaDevicePluginOption) error {
-	err := apiclient.CreateResourceWithFile(ctx, clientset, constants.ManifestsDir+"gpu/nvidia-device-plugin.yaml", option)
-	if err != nil {
-		return err
+// IsEnable tells whether the GPUs of the machine are prepared.
+func IsEnable(spec platformv1.MachineSpec) bool {
+	if spec.GPU != nil {
+		return spec.GPU.Enable
 	}
-	return nil
-}
-
-func IsEnable(labels map[string]string) bool {
-	return labels["nvidia-device-enable"] == "enable"
+	return spec.Labels[legacyEnableLabel] == "enable"
 }
 
+// MachineIsSupport tells whether the machine has an NVIDIA GPU.
 func MachineIsSupport(s ssh.Interface) bool {
 	// https://wiki.debian.org/NvidiaGraphicsDrivers#NVIDIA_Proprietary_Driver
-	_, err := s.CombinedOutput(`lspci -nn | egrep -i "3d|display|vga"`)
+	_, err := s.CombinedOutput(`lspci -nn | egrep -i "3d|display|vga" | grep -qi nvidia`)
 	return err == nil
 }
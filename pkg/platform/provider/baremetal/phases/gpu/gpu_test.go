@@ -0,0 +1,74 @@
+/*
+ * Copyright 2019 THL A29 Limited, a Tencent company.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+)
+
+func TestParseInventory(t *testing.T) {
+	out := "0, GPU-5a7c7ab6-2d3f-1b0e-7f3e-cf1c5d0f4e2a, Tesla T4, 15109, 470.57.02\n" +
+		"1, GPU-9e3b1f44-6c2a-8d1e-2b7a-4e6f0c9d1a3b, Tesla T4, 15109, 470.57.02\n"
+	status, err := parseInventory(out)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), status.Count)
+	assert.Equal(t, "470.57.02", status.DriverVersion)
+	assert.Equal(t, platformv1.GPUDevice{
+		Index:     1,
+		UUID:      "GPU-9e3b1f44-6c2a-8d1e-2b7a-4e6f0c9d1a3b",
+		Model:     "Tesla T4",
+		MemoryMiB: 15109,
+	}, status.Devices[1])
+
+	assert.Equal(t, map[string]string{
+		LabelGPUVendor: "nvidia",
+		LabelGPUCount:  "2",
+		LabelGPUModel:  "Tesla-T4",
+		LabelGPUMemory: "15109",
+		LabelGPUDriver: "470.57.02",
+	}, NodeLabels(status))
+
+	_, err = parseInventory("0, GPU-5a7c7ab6, Tesla T4, [N/A], 470.57.02\n")
+	assert.Error(t, err)
+}
+
+func TestParseAddonOption(t *testing.T) {
+	option, err := ParseAddonOption(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, AddonNvidiaDevicePlugin, option.Type)
+	assert.Equal(t, "nvcr.io/nvidia/k8s-device-plugin:v0.9.0", option.NvidiaDevicePluginImage)
+
+	option, err = ParseAddonOption(map[string]string{
+		"type":        "gpu-manager",
+		"registry":    "registry.local/gpu/",
+		"gpu-manager": "mirror.local/gpu-manager:v1.1.0",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, AddonGPUManager, option.Type)
+	assert.Equal(t, "mirror.local/gpu-manager:v1.1.0", option.GPUManagerImage)
+	assert.Equal(t, "registry.local/gpu/gpu-quota-admission:v1.0.0", option.GPUQuotaAdmissionImage)
+
+	recorded, err := ParseAddonOption(option.data())
+	assert.NoError(t, err)
+	assert.Equal(t, option, recorded)
+
+	_, err = ParseAddonOption(map[string]string{"type": "mig"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package keepalived
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/images"
+	"pml.io/april/pkg/util/ssh"
+	"pml.io/april/pkg/util/template"
+)
+
+// defaultRepository is the public repository of the keepalived image.
+const defaultRepository = "osixia/keepalived"
+
+// Image returns the image keepalived runs from at the tag of its component, pulled from
+// registry if set, else from its public repository.
+func Image(registry string) string {
+	component := images.Get().Keepalived
+	repository := defaultRepository
+	if registry != "" {
+		repository = strings.TrimSuffix(registry, "/") + "/" + component.Name
+	}
+	return repository + ":" + component.Tag
+}
+
+type Option struct {
+	// IP is the address of the master keepalived runs on.
+	IP string
+	// Peers are the addresses of the other masters.
+	Peers     []string
+	VIP       string
+	VRID      int32
+	Priority  int
+	Interface string
+	Image     string
+}
+
+// Install writes the keepalived config and its static pod to the master, the pod
+// is started by the kubelet along with the control plane.
+func Install(s ssh.Interface, option *Option) error {
+	if option.Interface == "" {
+		iface, err := InterfaceOf(s, option.IP)
+		if err != nil {
+			return err
+		}
+		option.Interface = iface
+	}
+
+	data, err := template.ParseFile(path.Join(constants.ConfDir, "keepalived/keepalived.conf"), option)
+	if err != nil {
+		return err
+	}
+	err = s.WriteFile(bytes.NewReader(data), constants.KeepavliedConfigFile)
+	if err != nil {
+		return errors.Wrapf(err, "write %s error", constants.KeepavliedConfigFile)
+	}
+
+	data, err = template.ParseFile(path.Join(constants.ConfDir, "keepalived/keepalived.yaml"), option)
+	if err != nil {
+		return err
+	}
+	err = s.WriteFile(bytes.NewReader(data), constants.KeepavlivedManifestFile)
+	if err != nil {
+		return errors.Wrapf(err, "write %s error", constants.KeepavlivedManifestFile)
+	}
+
+	return nil
+}
+
+// InterfaceOf returns the name of the network interface the ip is assigned to.
+func InterfaceOf(s ssh.Interface, ip string) (string, error) {
+	cmd := fmt.Sprintf(`ip -o addr show | awk '$4 ~ "^%s/" {print $2}'`, ip)
+	out, err := s.CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("run cmd(%s) error: %w", cmd, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("no interface has ip %s", ip)
+	}
+
+	return fields[0], nil
+}
+
+// DefaultVRID derives the virtual router id from the last byte of the vip, so that
+// clusters sharing a network don't collide by default.
+func DefaultVRID(vip string) int32 {
+	i := strings.LastIndex(vip, ".")
+	if i == -1 {
+		return 51
+	}
+	var id int32
+	if _, err := fmt.Sscanf(vip[i+1:], "%d", &id); err != nil || id <= 0 || id > 255 {
+		return 51
+	}
+
+	return id
+}
@@ -0,0 +1,14 @@
+package keepalived
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"pml.io/april/pkg/platform/provider/baremetal/images"
+)
+
+func TestImage(t *testing.T) {
+	tag := images.Get().Keepalived.Tag
+	assert.Equal(t, "osixia/keepalived:"+tag, Image(""))
+	assert.Equal(t, "registry.local/library/keepalived:"+tag, Image("registry.local/library/"))
+}
@@ -0,0 +1,107 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package kubeadm
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/util/ssh"
+)
+
+var (
+	// renewedCerts are the certs renewed by `kubeadm certs renew all`, which is
+	// `kubeadm alpha certs renew all` before 1.20. The CAs are not among them.
+	renewedCerts = []string{
+		"apiserver.crt",
+		"apiserver-kubelet-client.crt",
+		"apiserver-etcd-client.crt",
+		"front-proxy-client.crt",
+		"etcd/server.crt",
+		"etcd/peer.crt",
+		"etcd/healthcheck-client.crt",
+	}
+	renewedKubeConfigs = []string{
+		"admin.conf",
+		"controller-manager.conf",
+		"scheduler.conf",
+	}
+)
+
+// GetCertsExpiration returns when each cert renewed by RenewCerts expires, keyed by the
+// file the cert is in. Certs which don't exist on the master, e.g. of an external etcd,
+// are skipped.
+func GetCertsExpiration(s ssh.Interface) (map[string]time.Time, error) {
+	expiration := make(map[string]time.Time)
+	for _, name := range renewedCerts {
+		file := path.Join(constants.CertificatesDir, name)
+		data, err := readIfExist(s, file)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		certs, err := certutil.ParseCertsPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s error: %w", file, err)
+		}
+		expiration[name] = certs[0].NotAfter
+	}
+
+	for _, name := range renewedKubeConfigs {
+		file := path.Join(constants.KubernetesDir, name)
+		data, err := readIfExist(s, file)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			return nil, fmt.Errorf("load %s error: %w", file, err)
+		}
+		for _, info := range config.AuthInfos {
+			if info.ClientCertificateData == nil {
+				continue
+			}
+			certs, err := certutil.ParseCertsPEM(info.ClientCertificateData)
+			if err != nil {
+				return nil, fmt.Errorf("parse client cert of %s error: %w", file, err)
+			}
+			expiration[name] = certs[0].NotAfter
+		}
+	}
+
+	return expiration, nil
+}
+
+func readIfExist(s ssh.Interface, file string) ([]byte, error) {
+	exist, err := s.Exist(file)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	return s.ReadFile(file)
+}
@@ -30,6 +30,7 @@ import (
 	kubeadmapi "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm"
 	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
 	tokenphase "k8s.io/kubernetes/cmd/kubeadm/app/phases/bootstraptoken/node"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/pubkeypin"
 	"path"
 	"pml.io/april/pkg/util/apiclient"
 	"strings"
@@ -37,6 +38,7 @@ import (
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -46,6 +48,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	certutil "k8s.io/client-go/util/cert"
 	bootstrapsecretutil "k8s.io/cluster-bootstrap/util/secrets"
 	kubeadmv1beta2 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
 	"pml.io/april/pkg/platform/provider/baremetal/constants"
@@ -58,12 +61,16 @@ import (
 const (
 	kubeadmKubeletConf = "/usr/lib/systemd/system/kubelet.service.d/10-kubeadm.conf"
 
-	initCmd  = `kubeadm init phases {{.Phase}} --config={{.Config}}`
+	initCmd  = `kubeadm init phase {{.Phase}} --config={{.Config}}`
 	joinCmd  = `kubeadm join phase {{.Phase}} --config={{.Config}}`
 	resetCmd = `kubeadm reset phase {{.Phase}}`
 	// WillUpgrade is value of label platform.tkestack.io/need-upgrade
 	// machines with this value will upgrade it's node automatically one by one
 	WillUpgrade = "willUpgrade"
+
+	// BootstrapTokenTTL is how long the token of a joining node lives, it is deleted
+	// once the node is ready anyway.
+	BootstrapTokenTTL = time.Hour
 )
 
 var (
@@ -76,7 +83,8 @@ var (
 	unMigrataleComponents = []string{"tke-platform-api", "tke-platform-controller", "tke-registry-api", "tke-registry-controller", "influxdb"}
 )
 
-func Install(s ssh.Interface, version string) error {
+// Install installs kubeadm, the kubelet reads its extra args from environmentFile.
+func Install(s ssh.Interface, version string, environmentFile string) error {
 	dstFile, err := res.KubernetesNode.CopyToNode(s, version)
 	if err != nil {
 		return err
@@ -88,7 +96,10 @@ func Install(s ssh.Interface, version string) error {
 		return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
 	}
 
-	data, err := template.ParseFile(path.Join(constants.ConfDir, "kubeadm/10-kubeadm.conf"), nil)
+	option := map[string]interface{}{
+		"EnvironmentFile": environmentFile,
+	}
+	data, err := template.ParseFile(path.Join(constants.ConfDir, "kubeadm/10-kubeadm.conf"), option)
 	if err != nil {
 		return err
 	}
@@ -116,6 +127,9 @@ func Init(s ssh.Interface, kubeadmConfig *InitConfig, phase string, preActions .
 	if err != nil {
 		return err
 	}
+	if phase == "preflight" {
+		phase = fmt.Sprintf("preflight --ignore-preflight-errors=%s", strings.Join(ignoreErrors, ","))
+	}
 
 	cmd, err := template.ParseString(initCmd, map[string]interface{}{
 		"Phase":  phase,
@@ -134,11 +148,17 @@ func Init(s ssh.Interface, kubeadmConfig *InitConfig, phase string, preActions .
 	return nil
 }
 
-func Join(s ssh.Interface, config *kubeadmv1beta2.JoinConfiguration, phase string, endPointIPs []string) error {
+// Join runs the kubeadm join phase against the first reachable endpoint, the config
+// is written in the API version read by the kubeadm of the kubernetes version.
+func Join(s ssh.Interface, config *kubeadmv1beta2.JoinConfiguration, version string, phase string, endPointIPs []string) error {
 	var errs []error
 	for _, ip := range endPointIPs {
 		config.Discovery.BootstrapToken.APIServerEndpoint = ip + ":6443"
-		configData, err := MarshalToYAML(config)
+		obj, err := ConvertForVersion(config, version)
+		if err != nil {
+			return err
+		}
+		configData, err := MarshalToYAML(obj)
 		if err != nil {
 			return err
 		}
@@ -188,13 +208,22 @@ func Reset(s ssh.Interface, phase string) error {
 	return nil
 }
 
-func RenewCerts(s ssh.Interface) error {
+// certsCommandVersionConstraint matches the kubernetes versions whose kubeadm has the
+// certs command out of alpha, kubeadm 1.21 removed `kubeadm alpha certs`.
+const certsCommandVersionConstraint = ">= 1.20.0-0"
+
+// RenewCerts renews the certs of the master with the kubeadm of the kubernetes version,
+// and restarts the control plane.
+func RenewCerts(s ssh.Interface, version string) error {
 	err := fixKubeadmBug1753(s)
 	if err != nil {
 		return fmt.Errorf("fixKubeadmBug1753(https://github.com/kubernetes/kubeadm/issues/1753) error: %w", err)
 	}
 
-	cmd := "kubeadm alpha certs renew all"
+	cmd, err := renewCertsCmd(version)
+	if err != nil {
+		return err
+	}
 	_, err = s.CombinedOutput(cmd)
 	if err != nil {
 		return err
@@ -208,6 +237,18 @@ func RenewCerts(s ssh.Interface) error {
 	return nil
 }
 
+func renewCertsCmd(version string) (string, error) {
+	ga, err := apiclient.CheckVersion(strings.TrimPrefix(version, "v"), certsCommandVersionConstraint)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse kubernetes version %q", version)
+	}
+	if ga {
+		return "kubeadm certs renew all", nil
+	}
+
+	return "kubeadm alpha certs renew all", nil
+}
+
 // https://github.com/kubernetes/kubeadm/issues/1753
 func fixKubeadmBug1753(s ssh.Interface) error {
 	needUpdate := false
@@ -441,6 +482,16 @@ type UpgradeOption struct {
 //	return true, nil
 //}
 
+// Upgrade upgrades the control plane of the master to the version, the first master
+// applies the upgrade to the cluster and the others follow it.
+func Upgrade(s ssh.Interface, client kubernetes.Interface, version string, first bool) error {
+	if first {
+		return upgradeBootstrapNode(s, client, version)
+	}
+
+	return upgradeNode(s)
+}
+
 func checkKubeletVersion(client kubernetes.Interface, nodeName, version string, ignorePatchVersion bool) (same bool, err error) {
 	node, err := client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
 	if err != nil {
@@ -707,3 +758,88 @@ func CreateShortLivedBootstrapToken(client clientset.Interface) (string, error)
 	}
 	return tokenStr, nil
 }
+
+// EnsureBootstrapToken returns a valid bootstrap token carrying the description, a
+// short-lived one is created when there is none. Each joining node gets a token of
+// its own, so that it can be deleted as soon as the node has joined.
+func EnsureBootstrapToken(client clientset.Interface, description string) (string, error) {
+	secrets, err := listBootstrapTokens(client, description)
+	if err != nil {
+		return "", err
+	}
+	for i := range secrets {
+		if tokenStr, ok := ValidateSecretForSigning(&secrets[i]); ok {
+			return tokenStr, nil
+		}
+	}
+
+	tokenStr, err := bootstraputil.GenerateBootstrapToken()
+	if err != nil {
+		return "", errors.Wrap(err, "error generating token")
+	}
+	token, err := kubeadmapi.NewBootstrapTokenString(tokenStr)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating token")
+	}
+	tokens := []kubeadmapi.BootstrapToken{{
+		Token:       token,
+		Description: description,
+		TTL: &metav1.Duration{
+			Duration: BootstrapTokenTTL,
+		},
+		Usages: []string{"signing", "authentication"},
+		Groups: []string{kubeadmconstants.NodeBootstrapTokenAuthGroup},
+	}}
+	if err := tokenphase.CreateNewTokens(client, tokens); err != nil {
+		return "", errors.Wrap(err, "error creating token")
+	}
+
+	return tokenStr, nil
+}
+
+// DeleteBootstrapTokens deletes the bootstrap tokens carrying the description.
+func DeleteBootstrapTokens(client clientset.Interface, description string) error {
+	secrets, err := listBootstrapTokens(client, description)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		err := client.CoreV1().Secrets(metav1.NamespaceSystem).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func listBootstrapTokens(client clientset.Interface, description string) ([]v1.Secret, error) {
+	selector := fields.OneTermEqualSelector("type", string(bootstrapapi.SecretTypeBootstrapToken)).String()
+	secrets, err := client.CoreV1().Secrets(metav1.NamespaceSystem).List(context.TODO(), metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	var result []v1.Secret
+	for _, secret := range secrets.Items {
+		if bootstrapsecretutil.GetData(&secret, bootstrapapi.BootstrapTokenDescriptionKey) == description {
+			result = append(result, secret)
+		}
+	}
+
+	return result, nil
+}
+
+// CACertHashes returns the public key pins of the CA certs, which the joining nodes
+// validate the cluster-info discovered with the bootstrap token against.
+func CACertHashes(caCert []byte) ([]string, error) {
+	certs, err := certutil.ParseCertsPEM(caCert)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA cert error: %w", err)
+	}
+	hashes := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		hashes = append(hashes, pubkeypin.Hash(cert))
+	}
+
+	return hashes, nil
+}
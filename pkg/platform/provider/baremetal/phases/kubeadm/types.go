@@ -21,20 +21,27 @@ package kubeadm
 import (
 	"bytes"
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	kubeadmv1beta2 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
+	kubeadmv1beta3 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta3"
 	kubeletv1beta1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubelet/config/v1beta1"
 	kubeproxyv1alpha1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeproxy/config/v1alpha1"
+	"pml.io/april/pkg/util/apiclient"
 )
 
+// v1beta3VersionConstraint matches the kubernetes versions whose kubeadm reads v1beta3 configs.
+const v1beta3VersionConstraint = ">= 1.22.0-0"
+
 var Scheme = runtime.NewScheme()
 var Codecs = serializer.NewCodecFactory(Scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	kubeadmv1beta2.AddToScheme,
+	kubeadmv1beta3.AddToScheme,
 	kubeletv1beta1.AddToScheme,
 	kubeproxyv1alpha1.AddToScheme,
 }
@@ -52,6 +59,10 @@ type InitConfig struct {
 }
 
 func (c *InitConfig) Marshal() ([]byte, error) {
+	var version string
+	if c.ClusterConfiguration != nil {
+		version = c.ClusterConfiguration.KubernetesVersion
+	}
 	buf := new(bytes.Buffer)
 	v := reflect.ValueOf(*c)
 	for i := 0; i < v.NumField(); i++ {
@@ -62,6 +73,12 @@ func (c *InitConfig) Marshal() ([]byte, error) {
 		if !ok {
 			panic("no runtime.Object")
 		}
+		if version != "" {
+			var err error
+			if obj, err = ConvertForVersion(obj, version); err != nil {
+				return nil, err
+			}
+		}
 
 		yamlData, err := MarshalToYAML(obj)
 		if err != nil {
@@ -74,6 +91,28 @@ func (c *InitConfig) Marshal() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// ConvertForVersion converts the v1beta2 kubeadm configs to the config API version
+// read by the kubeadm of the kubernetes version, other objects are returned as is.
+func ConvertForVersion(obj runtime.Object, version string) (runtime.Object, error) {
+	useV1beta3, err := apiclient.CheckVersion(strings.TrimPrefix(version, "v"), v1beta3VersionConstraint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse kubernetes version %q", version)
+	}
+	if !useV1beta3 {
+		return obj, nil
+	}
+
+	switch o := obj.(type) {
+	case *kubeadmv1beta2.InitConfiguration:
+		return kubeadmv1beta3.ConvertInitConfiguration(o), nil
+	case *kubeadmv1beta2.ClusterConfiguration:
+		return kubeadmv1beta3.ConvertClusterConfiguration(o), nil
+	case *kubeadmv1beta2.JoinConfiguration:
+		return kubeadmv1beta3.ConvertJoinConfiguration(o), nil
+	}
+	return obj, nil
+}
+
 // MarshalToYaml marshals an object into yaml.
 func MarshalToYAML(obj runtime.Object) ([]byte, error) {
 	gvks, _, err := Scheme.ObjectKinds(obj)
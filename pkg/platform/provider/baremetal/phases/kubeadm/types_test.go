@@ -0,0 +1,64 @@
+package kubeadm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kubeadmv1beta2 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta2"
+	kubeadmv1beta3 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeadm/v1beta3"
+	kubeletv1beta1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubelet/config/v1beta1"
+)
+
+func TestConvertForVersion(t *testing.T) {
+	join := &kubeadmv1beta2.JoinConfiguration{CACertPath: "/etc/kubernetes/pki/ca.crt"}
+	tests := []struct {
+		version    string
+		useV1beta3 bool
+	}{
+		{"v1.21.5", false},
+		{"1.21.5", false},
+		{"v1.22.0-rc.0", true},
+		{"v1.22.2", true},
+		{"1.23.1", true},
+	}
+	for _, tt := range tests {
+		obj, err := ConvertForVersion(join, tt.version)
+		assert.NoError(t, err, tt.version)
+		_, ok := obj.(*kubeadmv1beta3.JoinConfiguration)
+		assert.Equal(t, tt.useV1beta3, ok, tt.version)
+	}
+
+	_, err := ConvertForVersion(join, "latest")
+	assert.Error(t, err)
+
+	// the configs of other components are not versioned by kubeadm.
+	kubelet := &kubeletv1beta1.KubeletConfiguration{}
+	obj, err := ConvertForVersion(kubelet, "v1.22.2")
+	assert.NoError(t, err)
+	assert.Same(t, kubelet, obj)
+}
+
+func TestInitConfigMarshal(t *testing.T) {
+	config := func(version string) *InitConfig {
+		return &InitConfig{
+			InitConfiguration: &kubeadmv1beta2.InitConfiguration{CertificateKey: "key"},
+			ClusterConfiguration: &kubeadmv1beta2.ClusterConfiguration{
+				KubernetesVersion: version,
+				DNS:               kubeadmv1beta2.DNS{Type: kubeadmv1beta2.CoreDNS},
+			},
+		}
+	}
+
+	data, err := config("v1.21.5").Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "apiVersion: kubeadm.k8s.io/v1beta2")
+	assert.NotContains(t, string(data), "kubeadm.k8s.io/v1beta3")
+	assert.Contains(t, string(data), "type: CoreDNS")
+
+	data, err = config("v1.22.2").Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "apiVersion: kubeadm.k8s.io/v1beta3")
+	assert.NotContains(t, string(data), "kubeadm.k8s.io/v1beta2")
+	assert.NotContains(t, string(data), "type: CoreDNS")
+	assert.Contains(t, string(data), "certificateKey: key")
+}
@@ -20,30 +20,40 @@ package kubeconfig
 
 import (
 	"bytes"
+	"fmt"
 	"pml.io/april/pkg/util/ssh"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
 )
 
+const kubeconfigFile = "/root/.kube/config" // fixme ssh not support $HOME or ~
+
 type Option struct {
 	MasterEndpoint string
 	ClusterName    string
 	CACert         []byte
+	User           string
 	Token          string
 }
 
 // Install creates all the requested kubeconfig files.
 func Install(s ssh.Interface, option *Option) error {
-	config := CreateWithToken(option.MasterEndpoint, option.ClusterName, "kubernetes-admin", option.CACert, option.Token)
+	config := CreateWithToken(option.MasterEndpoint, option.ClusterName, option.User, option.CACert, option.Token)
 	data, err := runtime.Encode(clientcmdlatest.Codec, config)
 	if err != nil {
 		return err
 	}
-	err = s.WriteFile(bytes.NewReader(data), "/root/.kube/config") // fixme ssh not support $HOME or ~
+	err = s.WriteFile(bytes.NewReader(data), kubeconfigFile)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Uninstall removes the kubeconfig file created by Install.
+func Uninstall(s ssh.Interface) error {
+	_, err := s.CombinedOutput(fmt.Sprintf("rm -f %s", kubeconfigFile))
+	return err
+}
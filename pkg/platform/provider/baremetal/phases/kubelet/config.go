@@ -0,0 +1,245 @@
+package kubelet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeletv1beta1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubelet/config/v1beta1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
+	"pml.io/april/pkg/util/apiclient"
+	"pml.io/april/pkg/util/ssh"
+)
+
+// ConfigFile is the kubelet configuration of the node, derived from the one of the
+// cluster. kubeadm rewrites /var/lib/kubelet/config.yaml from the kubelet-config
+// ConfigMap when joining, so the one of the node lives apart from it.
+const ConfigFile = "/etc/kubernetes/kubelet-config.yaml"
+
+// ConfigMapKey is the key of the kubelet configuration in the kubelet-config ConfigMap.
+const ConfigMapKey = "kubelet"
+
+// configMapVersionConstraint matches the kubernetes versions whose kubeadm uploads the
+// kubelet configuration without the minor in the name of the ConfigMap.
+const configMapVersionConstraint = ">= 1.24.0-0"
+
+const (
+	mebi = 1024 * 1024
+	gibi = 1024 * mebi
+)
+
+// ConfigOption is what the kubelet configuration of a node is derived from.
+type ConfigOption struct {
+	CPUCores int
+	MemoryMB int
+	// CgroupDriver is the one of the container runtime.
+	CgroupDriver string
+	// Overrides is a partial KubeletConfiguration in json applied at last.
+	Overrides []byte
+}
+
+// ConfigMapName returns the name of the ConfigMap in kube-system kubeadm uploads the
+// kubelet configuration of the cluster to.
+func ConfigMapName(version string) (string, error) {
+	unversioned, err := apiclient.CheckVersion(strings.TrimPrefix(version, "v"), configMapVersionConstraint)
+	if err != nil {
+		return "", err
+	}
+	if unversioned {
+		return "kubelet-config", nil
+	}
+	v, err := semver.NewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("kubelet-config-%d.%d", v.Major(), v.Minor()), nil
+}
+
+// Render derives the kubelet configuration of the node from base, the one of the
+// cluster, and points kubelet at it by its environment file: the --config flag of
+// KUBELET_EXTRA_ARGS comes last and wins over the one of kubeadm. Rendered before the
+// join, kubelet starts with it at once; a running kubelet is restarted on changes.
+func Render(s ssh.Interface, base []byte, environmentFile string, option *ConfigOption) error {
+	cfg := new(kubeletv1beta1.KubeletConfiguration)
+	err := runtime.DecodeInto(kubeadm.Codecs.UniversalDeserializer(), base, cfg)
+	if err != nil {
+		return fmt.Errorf("decode the kubelet configuration of the cluster error: %w", err)
+	}
+
+	err = Complete(cfg, option)
+	if err != nil {
+		return err
+	}
+	err = Validate(cfg, option)
+	if err != nil {
+		return err
+	}
+
+	data, err := kubeadm.MarshalToYAML(cfg)
+	if err != nil {
+		return err
+	}
+	current, err := s.ReadFile(ConfigFile)
+	changed := err != nil || !bytes.Equal(current, data)
+	if changed {
+		err = s.WriteFile(bytes.NewReader(data), ConfigFile)
+		if err != nil {
+			return err
+		}
+	}
+	env := fmt.Sprintf("KUBELET_EXTRA_ARGS=\"--config=%s\"\n", ConfigFile)
+	current, err = s.ReadFile(environmentFile)
+	if err != nil || string(current) != env {
+		err = s.WriteFile(strings.NewReader(env), environmentFile)
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	_, err = s.CombinedOutput("if systemctl is-active -q kubelet; then systemctl restart kubelet; fi")
+	return err
+}
+
+// Complete sets the reserved resources, eviction thresholds, max pods and cgroup
+// driver of the node, the overrides of the option win.
+func Complete(cfg *kubeletv1beta1.KubeletConfiguration, option *ConfigOption) error {
+	cfg.KubeReserved = map[string]string{
+		"cpu":    fmt.Sprintf("%dm", reservedCPU(option.CPUCores)),
+		"memory": fmt.Sprintf("%dMi", reservedMemory(int64(option.MemoryMB)*mebi)/mebi),
+	}
+	cfg.SystemReserved = map[string]string{
+		"cpu":    "100m",
+		"memory": "256Mi",
+	}
+	// the defaults of kubelet are dropped as soon as one is set, keep them all.
+	cfg.EvictionHard = map[string]string{
+		"memory.available":  "100Mi",
+		"nodefs.available":  "10%",
+		"nodefs.inodesFree": "5%",
+		"imagefs.available": "15%",
+	}
+	cfg.MaxPods = maxPods(option.MemoryMB)
+	if option.CgroupDriver != "" {
+		cfg.CgroupDriver = option.CgroupDriver
+	}
+
+	if len(option.Overrides) > 0 {
+		err := json.Unmarshal(option.Overrides, cfg)
+		if err != nil {
+			return fmt.Errorf("apply kubelet configuration overrides error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the configuration leaves the node something to run pods with.
+func Validate(cfg *kubeletv1beta1.KubeletConfiguration, option *ConfigOption) error {
+	if cfg.CgroupDriver != "systemd" && cfg.CgroupDriver != "cgroupfs" {
+		return fmt.Errorf("invalid cgroup driver %q", cfg.CgroupDriver)
+	}
+	if option.CgroupDriver != "" && cfg.CgroupDriver != option.CgroupDriver {
+		return fmt.Errorf("cgroup driver %q does not match %q of the container runtime", cfg.CgroupDriver, option.CgroupDriver)
+	}
+	if cfg.MaxPods <= 0 {
+		return fmt.Errorf("invalid max pods %d", cfg.MaxPods)
+	}
+	for signal, threshold := range cfg.EvictionHard {
+		if len(threshold) > 0 && threshold[len(threshold)-1] == '%' {
+			continue
+		}
+		if _, err := resource.ParseQuantity(threshold); err != nil {
+			return fmt.Errorf("invalid eviction threshold %s=%s: %w", signal, threshold, err)
+		}
+	}
+
+	capacity := map[string]*resource.Quantity{
+		"cpu":    resource.NewMilliQuantity(int64(option.CPUCores)*1000, resource.DecimalSI),
+		"memory": resource.NewQuantity(int64(option.MemoryMB)*mebi, resource.BinarySI),
+	}
+	for name, total := range capacity {
+		reserved := resource.Quantity{}
+		for _, reservation := range []map[string]string{cfg.KubeReserved, cfg.SystemReserved} {
+			if v, ok := reservation[name]; ok {
+				q, err := resource.ParseQuantity(v)
+				if err != nil {
+					return fmt.Errorf("invalid reserved %s %q: %w", name, v, err)
+				}
+				reserved.Add(q)
+			}
+		}
+		if reserved.Cmp(*total) >= 0 {
+			return fmt.Errorf("reserved %s %s leaves nothing of %s", name, reserved.String(), total.String())
+		}
+	}
+
+	return nil
+}
+
+// reservedCPU returns the millicores kubernetes keeps for itself: 6% of the first
+// core, 1% of the second, 0.5% of the next two and 0.25% of the rest.
+func reservedCPU(cores int) int64 {
+	var millicores float64
+	for i := 0; i < cores; i++ {
+		switch {
+		case i == 0:
+			millicores += 60
+		case i == 1:
+			millicores += 10
+		case i < 4:
+			millicores += 5
+		default:
+			millicores += 2.5
+		}
+	}
+	return int64(millicores)
+}
+
+// reservedMemory returns the bytes kubernetes keeps for itself: 25% of the first
+// 4GiB, 20% of the next 4GiB, 10% of the next 8GiB, 6% of the next 112GiB and
+// 2% of the rest.
+func reservedMemory(memory int64) int64 {
+	tiers := []struct {
+		size    int64
+		percent int64
+	}{
+		{4 * gibi, 25},
+		{4 * gibi, 20},
+		{8 * gibi, 10},
+		{112 * gibi, 6},
+		{-1, 2},
+	}
+	var reserved int64
+	for _, tier := range tiers {
+		size := memory
+		if tier.size >= 0 && size > tier.size {
+			size = tier.size
+		}
+		reserved += size * tier.percent / 100
+		memory -= size
+		if memory <= 0 {
+			break
+		}
+	}
+	return reserved
+}
+
+// maxPods allows 16 pods per GiB of memory, between 32 and the default 110.
+func maxPods(memoryMB int) int32 {
+	pods := int32(memoryMB * 16 / 1024)
+	if pods < 32 {
+		return 32
+	}
+	if pods > 110 {
+		return 110
+	}
+	return pods
+}
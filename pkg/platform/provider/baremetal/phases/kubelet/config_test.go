@@ -0,0 +1,80 @@
+package kubelet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kubeletv1beta1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubelet/config/v1beta1"
+)
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   ConfigOption
+		reserved map[string]string
+		maxPods  int32
+	}{
+		{"1 core 2GiB", ConfigOption{CPUCores: 1, MemoryMB: 2048}, map[string]string{"cpu": "60m", "memory": "512Mi"}, 32},
+		{"2 cores 4GiB", ConfigOption{CPUCores: 2, MemoryMB: 4096}, map[string]string{"cpu": "70m", "memory": "1024Mi"}, 64},
+		{"4 cores 8GiB", ConfigOption{CPUCores: 4, MemoryMB: 8192}, map[string]string{"cpu": "80m", "memory": "1843Mi"}, 110},
+		{"8 cores 16GiB", ConfigOption{CPUCores: 8, MemoryMB: 16384}, map[string]string{"cpu": "90m", "memory": "2662Mi"}, 110},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := new(kubeletv1beta1.KubeletConfiguration)
+			assert.NoError(t, Complete(cfg, &tt.option))
+			assert.Equal(t, tt.reserved, cfg.KubeReserved)
+			assert.Equal(t, tt.maxPods, cfg.MaxPods)
+		})
+	}
+}
+
+func TestCompleteOverrides(t *testing.T) {
+	cfg := &kubeletv1beta1.KubeletConfiguration{CgroupDriver: "cgroupfs"}
+	option := &ConfigOption{
+		CPUCores:     2,
+		MemoryMB:     4096,
+		CgroupDriver: "systemd",
+		Overrides:    []byte(`{"maxPods":50,"kubeReserved":{"cpu":"200m"}}`),
+	}
+	assert.NoError(t, Complete(cfg, option))
+	assert.Equal(t, "systemd", cfg.CgroupDriver)
+	assert.Equal(t, int32(50), cfg.MaxPods)
+	// the overrides are merged into the derived reservations.
+	assert.Equal(t, map[string]string{"cpu": "200m", "memory": "1024Mi"}, cfg.KubeReserved)
+
+	option.Overrides = []byte(`{"maxPods":`)
+	assert.Error(t, Complete(cfg, option))
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		runtime   string
+		wantErr   bool
+	}{
+		{"derived", ``, "systemd", false},
+		{"no cgroup driver", ``, "", true},
+		{"cgroup driver of the runtime", `{"cgroupDriver":"cgroupfs"}`, "systemd", true},
+		{"invalid max pods", `{"maxPods":-1}`, "systemd", true},
+		{"percent eviction threshold", `{"evictionHard":{"memory.available":"5%"}}`, "systemd", false},
+		{"invalid eviction threshold", `{"evictionHard":{"memory.available":"lots"}}`, "systemd", true},
+		{"invalid reservation", `{"systemReserved":{"cpu":"lots"}}`, "systemd", true},
+		{"reserved all memory", `{"systemReserved":{"memory":"4Gi"}}`, "systemd", true},
+		{"reserved all cpu", `{"kubeReserved":{"cpu":"2"}}`, "systemd", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := new(kubeletv1beta1.KubeletConfiguration)
+			option := &ConfigOption{CPUCores: 2, MemoryMB: 4096, CgroupDriver: tt.runtime, Overrides: []byte(tt.overrides)}
+			assert.NoError(t, Complete(cfg, option))
+			err := Validate(cfg, option)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package kubeproxy renders the proxy mode of a cluster and tells the kernel modules it needs.
+package kubeproxy
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	kubeproxyv1alpha1 "pml.io/april/pkg/platform/provider/baremetal/apis/kubeproxy/config/v1alpha1"
+	"pml.io/april/pkg/platform/provider/baremetal/phases/kubeadm"
+)
+
+const (
+	ModeIPTables = "iptables"
+	ModeIPVS     = "ipvs"
+
+	DefaultIPVSScheduler = "rr"
+
+	// configMap is the ConfigMap kubeadm stores the configuration of kube-proxy in.
+	configMap = "kube-proxy"
+	configKey = "config.conf"
+)
+
+// IPVSSchedulers are the schedulers kube-proxy supports, each is a kernel module ip_vs_<name>.
+var IPVSSchedulers = []string{"rr", "wrr", "lc", "wlc", "lblc", "lblcr", "sh", "dh", "sed", "nq"}
+
+// Configuration renders the kube-proxy settings of the cluster for kubeadm init.
+func Configuration(spec *platformv1.ClusterKubeProxy) *kubeproxyv1alpha1.KubeProxyConfiguration {
+	cfg := &kubeproxyv1alpha1.KubeProxyConfiguration{
+		Mode: ModeIPTables,
+	}
+	if spec == nil {
+		return cfg
+	}
+	if spec.Mode != "" {
+		cfg.Mode = kubeproxyv1alpha1.ProxyMode(spec.Mode)
+	}
+	if cfg.Mode == ModeIPVS {
+		cfg.IPVS.Scheduler = scheduler(spec.IPVSScheduler)
+	}
+	cfg.Conntrack = kubeproxyv1alpha1.KubeProxyConntrackConfiguration{
+		MaxPerCore:            spec.ConntrackMaxPerCore,
+		Min:                   spec.ConntrackMin,
+		TCPEstablishedTimeout: spec.ConntrackTCPEstablishedTimeout,
+		TCPCloseWaitTimeout:   spec.ConntrackTCPCloseWaitTimeout,
+	}
+
+	return cfg
+}
+
+// Get returns the configuration of kube-proxy in a running cluster, clusters not
+// installed by kubeadm run the iptables mode.
+func Get(ctx context.Context, client kubernetes.Interface) (*kubeproxyv1alpha1.KubeProxyConfiguration, error) {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, configMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return Configuration(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(kubeproxyv1alpha1.KubeProxyConfiguration)
+	err = runtime.DecodeInto(kubeadm.Codecs.UniversalDeserializer(), []byte(cm.Data[configKey]), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s of ConfigMap %s error: %w", configKey, configMap, err)
+	}
+	return cfg, nil
+}
+
+// KernelModules returns the modules the proxy mode needs.
+func KernelModules(cfg *kubeproxyv1alpha1.KubeProxyConfiguration) []string {
+	modules := []string{"iptable_nat", "nf_conntrack"}
+	if cfg.Mode == ModeIPVS {
+		modules = append(modules, "ip_vs", "ip_vs_"+scheduler(cfg.IPVS.Scheduler))
+	}
+	return modules
+}
+
+func scheduler(name string) string {
+	if name == "" {
+		return DefaultIPVSScheduler
+	}
+	return name
+}
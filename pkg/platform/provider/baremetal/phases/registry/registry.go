@@ -0,0 +1,243 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package registry renders the registries of a machine into the configuration of
+// docker, containerd and kubelet.
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/util/ssh"
+)
+
+const (
+	dockerCertsDir     = "/etc/docker/certs.d"
+	containerdCertsDir = "/etc/containerd/certs.d"
+	// dockerConfigFile is read by docker pull, kubeletConfigFile by kubelet for
+	// the pods without image pull secrets.
+	dockerConfigFile  = "/root/.docker/config.json"
+	kubeletConfigFile = "/var/lib/kubelet/config.json"
+
+	dockerHub = "docker.io"
+
+	containerdConfigFile = "/etc/containerd/config.toml"
+	// criRegistrySection configures the registries of the cri plugin of containerd.
+	criRegistrySection = `[plugins."io.containerd.grpc.v1.cri".registry]`
+)
+
+// Auths are the entries of the auths of a docker config.json, keyed by registry host.
+type Auths map[string]json.RawMessage
+
+// ParseAuths returns the auths of a kubernetes.io/dockerconfigjson secret the hosts need.
+func ParseAuths(dockerConfigJSON []byte, hosts []string) (Auths, error) {
+	config := struct {
+		Auths Auths `json:"auths"`
+	}{}
+	err := json.Unmarshal(dockerConfigJSON, &config)
+	if err != nil {
+		return nil, fmt.Errorf("parse docker config error: %w", err)
+	}
+
+	auths := make(Auths)
+	for key, auth := range config.Auths {
+		for _, host := range hosts {
+			if Host(key) == host {
+				auths[host] = auth
+			}
+		}
+	}
+	return auths, nil
+}
+
+// Host returns the host of a registry or mirror, which may be given as an url.
+func Host(registry string) string {
+	if u, err := url.Parse(registry); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return strings.TrimSuffix(registry, "/")
+}
+
+// Hosts returns the registry and the hosts of its mirrors.
+func Hosts(r platformv1.Registry) []string {
+	hosts := []string{Host(r.Host)}
+	for _, mirror := range r.Mirrors {
+		hosts = append(hosts, Host(mirror))
+	}
+	return hosts
+}
+
+// DockerOptions returns the insecure registries and mirrors of the docker daemon,
+// docker only knows mirrors of docker hub.
+func DockerOptions(registries []platformv1.Registry) (insecure []string, mirrors []string) {
+	for _, r := range registries {
+		if r.Insecure {
+			insecure = append(insecure, Hosts(r)...)
+		}
+		if Host(r.Host) == dockerHub {
+			for _, mirror := range r.Mirrors {
+				mirrors = append(mirrors, mirrorURL(mirror, r.Insecure))
+			}
+		}
+	}
+	return insecure, mirrors
+}
+
+// Install writes the CA bundles, the containerd mirrors and the credentials of the registries.
+func Install(s ssh.Interface, registries []platformv1.Registry, auths Auths) error {
+	for _, r := range registries {
+		if len(r.CABundle) > 0 {
+			for _, host := range Hosts(r) {
+				for _, dir := range []string{dockerCertsDir, containerdCertsDir} {
+					err := s.WriteFile(bytes.NewReader(r.CABundle), path.Join(dir, host, "ca.crt"))
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		err := s.WriteFile(strings.NewReader(hostsTOML(r)), path.Join(containerdCertsDir, Host(r.Host), "hosts.toml"))
+		if err != nil {
+			return err
+		}
+	}
+	err := configureContainerd(s)
+	if err != nil {
+		return err
+	}
+
+	if len(auths) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(map[string]Auths{"auths": auths}, "", "  ")
+	if err != nil {
+		return err
+	}
+	for _, file := range []string{dockerConfigFile, kubeletConfigFile} {
+		err = s.WriteFile(bytes.NewReader(data), file)
+		if err != nil {
+			return err
+		}
+		_, err = s.CombinedOutput(fmt.Sprintf("chmod 600 %s", file))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configureContainerd points the cri plugin of containerd at the hosts.toml files of
+// the registries, and restarts containerd if it runs.
+func configureContainerd(s ssh.Interface) error {
+	var config string
+	if ok, err := s.Exist(containerdConfigFile); err != nil {
+		return err
+	} else if ok {
+		data, err := s.ReadFile(containerdConfigFile)
+		if err != nil {
+			return err
+		}
+		config = string(data)
+	}
+	config, changed := withConfigPath(config)
+	if !changed {
+		return nil
+	}
+	err := s.WriteFile(strings.NewReader(config), containerdConfigFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.CombinedOutput("if systemctl is-active -q containerd; then systemctl restart containerd; fi")
+	return err
+}
+
+// withConfigPath sets the config_path of the registries of the cri plugin in the
+// containerd config, a config_path set already is left alone.
+func withConfigPath(config string) (string, bool) {
+	line := fmt.Sprintf("  config_path = %q", containerdCertsDir)
+	i := strings.Index(config, criRegistrySection)
+	if i == -1 {
+		if strings.TrimSpace(config) == "" {
+			config = "version = 2\n"
+		}
+		return strings.TrimRight(config, "\n") + "\n\n" + criRegistrySection + "\n" + line + "\n", true
+	}
+
+	end := i + len(criRegistrySection)
+	body := config[end:]
+	if next := strings.Index(body, "\n["); next != -1 {
+		body = body[:next]
+	}
+	for _, l := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), "config_path") {
+			return config, false
+		}
+	}
+
+	return config[:end] + "\n" + line + config[end:], true
+}
+
+// hostsTOML renders the registry in the hosts.toml format of containerd.
+func hostsTOML(r platformv1.Registry) string {
+	host := Host(r.Host)
+	server := "https://" + host
+	if host == dockerHub {
+		server = "https://registry-1.docker.io"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "server = %q\n", server)
+	for _, mirror := range r.Mirrors {
+		fmt.Fprintf(&b, "\n[host.%q]\n", mirrorURL(mirror, r.Insecure))
+		b.WriteString("  capabilities = [\"pull\", \"resolve\"]\n")
+		writeHostOptions(&b, r, Host(mirror))
+	}
+	fmt.Fprintf(&b, "\n[host.%q]\n", server)
+	b.WriteString("  capabilities = [\"pull\", \"resolve\", \"push\"]\n")
+	writeHostOptions(&b, r, host)
+	return b.String()
+}
+
+func writeHostOptions(b *strings.Builder, r platformv1.Registry, host string) {
+	if len(r.CABundle) > 0 {
+		fmt.Fprintf(b, "  ca = %q\n", path.Join(containerdCertsDir, host, "ca.crt"))
+	}
+	if r.Insecure {
+		b.WriteString("  skip_verify = true\n")
+	}
+}
+
+// mirrorURL adds the scheme to a mirror given as a host.
+func mirrorURL(mirror string, insecure bool) string {
+	if strings.Contains(mirror, "://") {
+		return strings.TrimSuffix(mirror, "/")
+	}
+	if insecure {
+		return "http://" + mirror
+	}
+	return "https://" + mirror
+}
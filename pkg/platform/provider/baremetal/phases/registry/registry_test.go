@@ -0,0 +1,20 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithConfigPath(t *testing.T) {
+	config, changed := withConfigPath("")
+	assert.True(t, changed)
+	assert.Equal(t, "version = 2\n\n"+criRegistrySection+"\n  config_path = \"/etc/containerd/certs.d\"\n", config)
+
+	config, changed = withConfigPath(config)
+	assert.False(t, changed)
+
+	config, changed = withConfigPath("version = 2\n\n" + criRegistrySection + "\n\n[plugins.\"io.containerd.grpc.v1.cri\".containerd]\n  config_path = \"x\"\n")
+	assert.True(t, changed)
+	assert.Contains(t, config, criRegistrySection+"\n  config_path = \"/etc/containerd/certs.d\"\n")
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/util/ssh"
+)
+
+const (
+	// LabelResourceType labels the node with the resource type of its machine.
+	LabelResourceType = "pml.io/resource-type"
+	// LabelMachine labels the local persistent volumes with the name of their machine.
+	LabelMachine = "pml.io/machine"
+	// TaintStorage keeps the pods which don't tolerate it off the storage machines, the
+	// hybrid machines are not tainted.
+	TaintStorage = "pml.io/storage"
+
+	DefaultFSType           = "xfs"
+	DefaultStorageClassName = "april-local-storage"
+
+	// MountRoot is the directory the disks are mounted under, by their names.
+	MountRoot = "/mnt/disks"
+)
+
+// mkfsCommands are the commands formatting a disk with the supported filesystems.
+var mkfsCommands = map[string]string{
+	"xfs":  "mkfs.xfs %s",
+	"ext4": "mkfs.ext4 -F %s",
+}
+
+// fsPackages are the packages of the tools of the supported filesystems.
+var fsPackages = map[string]string{
+	"xfs":  "xfsprogs",
+	"ext4": "e2fsprogs",
+}
+
+// FSPackage returns the package of the tools of the filesystem.
+func FSPackage(fsType string) string {
+	return fsPackages[fsType]
+}
+
+// IsEnable tells whether the disks of the machine are provisioned.
+func IsEnable(spec platformv1.MachineSpec) bool {
+	return spec.ResourceType == platformv1.TypeStorage || spec.ResourceType == platformv1.TypeHybrid
+}
+
+// Option returns the storage of the machine with the defaults set.
+func Option(spec platformv1.MachineSpec) (*platformv1.MachineStorage, error) {
+	option := &platformv1.MachineStorage{}
+	if spec.Storage != nil {
+		option = spec.Storage.DeepCopy()
+	}
+	if option.FSType == "" {
+		option.FSType = DefaultFSType
+	}
+	if option.StorageClassName == "" {
+		option.StorageClassName = DefaultStorageClassName
+	}
+	if _, ok := mkfsCommands[option.FSType]; !ok {
+		return nil, fmt.Errorf("unsupported filesystem %q, it must be xfs or ext4", option.FSType)
+	}
+
+	return option, nil
+}
+
+// NodeLabels returns the labels of the node of the machine.
+func NodeLabels(spec platformv1.MachineSpec) map[string]string {
+	if spec.ResourceType == "" {
+		return nil
+	}
+
+	return map[string]string{LabelResourceType: string(spec.ResourceType)}
+}
+
+// NodeTaints returns the taints of the node of the machine, only a storage machine is
+// tainted.
+func NodeTaints(spec platformv1.MachineSpec) []corev1.Taint {
+	if spec.ResourceType != platformv1.TypeStorage {
+		return nil
+	}
+
+	return []corev1.Taint{{Key: TaintStorage, Value: "true", Effect: corev1.TaintEffectNoSchedule}}
+}
+
+// Disk is a disk of a machine reported by lsblk.
+type Disk struct {
+	Device     string
+	Size       int64
+	FSType     string
+	MountPoint string
+	// Partitioned tells the disk has partitions, or holders like LVM or RAID.
+	Partitioned bool
+}
+
+// MountPath returns where the disk is mounted once provisioned.
+func (d *Disk) MountPath() string {
+	return path.Join(MountRoot, path.Base(d.Device))
+}
+
+// Free tells whether the disk isn't used by the system, i.e. it is empty or
+// provisioned already. A disk with a filesystem which is not mounted at its mount path
+// may hold data, so it is not free.
+func (d *Disk) Free() bool {
+	if d.Partitioned {
+		return false
+	}
+	return d.MountPoint == d.MountPath() || (d.MountPoint == "" && d.FSType == "")
+}
+
+// Disks returns the disks of the machine.
+func Disks(s ssh.Interface) ([]Disk, error) {
+	cmd := "lsblk -P -b -p -o NAME,TYPE,SIZE,FSTYPE,MOUNTPOINT,PKNAME"
+	stdout, stderr, exit, err := s.Exec(cmd)
+	if err != nil || exit != 0 {
+		return nil, fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	}
+
+	return parseDisks(stdout)
+}
+
+var lsblkPair = regexp.MustCompile(`([A-Z:]+)="([^"]*)"`)
+
+func parseDisks(out string) ([]Disk, error) {
+	var (
+		disks   []Disk
+		parents = make(map[string]bool)
+	)
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		row := make(map[string]string)
+		for _, pair := range lsblkPair.FindAllStringSubmatch(line, -1) {
+			row[pair[1]] = pair[2]
+		}
+		if row["PKNAME"] != "" {
+			parents[row["PKNAME"]] = true
+		}
+		if row["TYPE"] != "disk" {
+			continue
+		}
+		size, err := strconv.ParseInt(row["SIZE"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q of lsblk: %w", row["SIZE"], err)
+		}
+		disks = append(disks, Disk{
+			Device:     row["NAME"],
+			Size:       size,
+			FSType:     row["FSTYPE"],
+			MountPoint: row["MOUNTPOINT"],
+		})
+	}
+	for i := range disks {
+		disks[i].Partitioned = parents[disks[i].Device]
+	}
+
+	return disks, nil
+}
+
+// Provision formats the disk with the filesystem, and mounts it at its mount path by an
+// entry of fstab. A disk with partitions, mounted elsewhere or with a filesystem is
+// refused, unless fstab mounts the filesystem at the mount path already, i.e. an
+// earlier provision of the disk made it.
+func Provision(s ssh.Interface, disk Disk, fsType string) error {
+	mountPath := disk.MountPath()
+	switch {
+	case disk.MountPoint == mountPath:
+		return nil
+	case disk.Partitioned:
+		return fmt.Errorf("disk %s has partitions", disk.Device)
+	case disk.MountPoint != "":
+		return fmt.Errorf("disk %s is mounted at %s", disk.Device, disk.MountPoint)
+	case disk.FSType != "" && disk.FSType != fsType:
+		return fmt.Errorf("disk %s has a %s filesystem", disk.Device, disk.FSType)
+	}
+
+	if disk.FSType == "" {
+		cmd := fmt.Sprintf(mkfsCommands[fsType], disk.Device)
+		_, stderr, exit, err := s.Exec(cmd)
+		if err != nil || exit != 0 {
+			return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+		}
+	}
+	cmd := fmt.Sprintf("blkid -s UUID -o value %s", disk.Device)
+	stdout, stderr, exit, err := s.Exec(cmd)
+	if err != nil || exit != 0 {
+		return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	}
+	uuid := strings.TrimSpace(stdout)
+	if uuid == "" {
+		return fmt.Errorf("disk %s has no UUID", disk.Device)
+	}
+	if disk.FSType != "" {
+		cmd = fmt.Sprintf("grep -q '^UUID=%s %s ' /etc/fstab", uuid, mountPath)
+		_, stderr, exit, err = s.Exec(cmd)
+		if err != nil || exit > 1 {
+			return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+		}
+		if exit != 0 {
+			return fmt.Errorf("disk %s has a %s filesystem which april didn't make", disk.Device, disk.FSType)
+		}
+	}
+
+	cmd = fmt.Sprintf("mkdir -p %[2]s && (grep -q '^UUID=%[1]s ' /etc/fstab || echo 'UUID=%[1]s %[2]s %[3]s defaults,nofail 0 2' >> /etc/fstab) && mount %[2]s",
+		uuid, mountPath, fsType)
+	_, stderr, exit, err = s.Exec(cmd)
+	if err != nil || exit != 0 {
+		return fmt.Errorf("exec %q failed:exit %d:stderr %s:error %s", cmd, exit, stderr, err)
+	}
+
+	return nil
+}
+
+// PersistentVolumeName returns the name of the local persistent volume of the disk.
+func PersistentVolumeName(machineName string, device string) string {
+	return fmt.Sprintf("local-%s-%s", machineName, path.Base(device))
+}
+
+// EnsureStorageClass creates the class of the local persistent volumes unless it
+// exists, the volumes are bound once a pod using them is scheduled.
+func EnsureStorageClass(ctx context.Context, client clientset.Interface, name string) error {
+	mode := storagev1.VolumeBindingWaitForFirstConsumer
+	_, err := client.StorageV1().StorageClasses().Create(ctx, &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: name},
+		Provisioner:       "kubernetes.io/no-provisioner",
+		VolumeBindingMode: &mode,
+	}, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create storage class %s error: %w", name, err)
+	}
+
+	return nil
+}
+
+// EnsurePersistentVolume creates the local persistent volume of the disk mounted on the
+// node unless it exists.
+func EnsurePersistentVolume(ctx context.Context, client clientset.Interface, machineName string, nodeName string,
+	storageClassName string, disk platformv1.DiskStatus) (string, error) {
+	name := PersistentVolumeName(machineName, disk.Device)
+	mode := corev1.PersistentVolumeFilesystem
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{LabelMachine: machineName},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:                      corev1.ResourceList{corev1.ResourceStorage: disk.Size},
+			AccessModes:                   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			StorageClassName:              storageClassName,
+			VolumeMode:                    &mode,
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				Local: &corev1.LocalVolumeSource{Path: disk.MountPath},
+			},
+			NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      corev1.LabelHostname,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{nodeName},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	_, err := client.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("create persistent volume %s error: %w", name, err)
+	}
+
+	return name, nil
+}
+
+// RemovePersistentVolumes deletes the local persistent volumes of the machine, the data
+// on the disks is kept.
+func RemovePersistentVolumes(ctx context.Context, client clientset.Interface, machineName string) error {
+	err := client.CoreV1().PersistentVolumes().DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: LabelMachine + "=" + machineName,
+	})
+	if err != nil {
+		return fmt.Errorf("delete the persistent volumes of machine %s error: %w", machineName, err)
+	}
+
+	return nil
+}
+
+// Capacity returns the total size of the provisioned disks.
+func Capacity(disks []platformv1.DiskStatus) resource.Quantity {
+	capacity := resource.NewQuantity(0, resource.BinarySI)
+	for _, disk := range disks {
+		if disk.MountPath != "" {
+			capacity.Add(disk.Size)
+		}
+	}
+
+	return *capacity
+}
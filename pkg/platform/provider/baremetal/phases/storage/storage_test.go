@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDisks(t *testing.T) {
+	out := `NAME="/dev/sda" TYPE="disk" SIZE="53687091200" FSTYPE="" MOUNTPOINT="" PKNAME=""
+NAME="/dev/sda1" TYPE="part" SIZE="53686042624" FSTYPE="xfs" MOUNTPOINT="/" PKNAME="/dev/sda"
+NAME="/dev/sdb" TYPE="disk" SIZE="107374182400" FSTYPE="" MOUNTPOINT="" PKNAME=""
+NAME="/dev/sdc" TYPE="disk" SIZE="107374182400" FSTYPE="xfs" MOUNTPOINT="/mnt/disks/sdc" PKNAME=""
+NAME="/dev/sdd" TYPE="disk" SIZE="107374182400" FSTYPE="ext4" MOUNTPOINT="" PKNAME=""
+NAME="/dev/sr0" TYPE="rom" SIZE="1073741312" FSTYPE="" MOUNTPOINT="" PKNAME=""
+`
+	disks, err := parseDisks(out)
+	assert.NoError(t, err)
+	assert.Equal(t, []Disk{
+		{Device: "/dev/sda", Size: 53687091200, Partitioned: true},
+		{Device: "/dev/sdb", Size: 107374182400},
+		{Device: "/dev/sdc", Size: 107374182400, FSType: "xfs", MountPoint: "/mnt/disks/sdc"},
+		{Device: "/dev/sdd", Size: 107374182400, FSType: "ext4"},
+	}, disks)
+
+	assert.False(t, disks[0].Free())
+	assert.True(t, disks[1].Free())
+	assert.True(t, disks[2].Free())
+	assert.False(t, disks[3].Free())
+	assert.Equal(t, "/mnt/disks/sdb", disks[1].MountPath())
+}
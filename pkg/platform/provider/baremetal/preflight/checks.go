@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"fmt"
 	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/platform/provider/baremetal/res"
 	typesv1 "pml.io/april/pkg/platform/provider/type"
 	"pml.io/april/pkg/util/ssh"
 	"strconv"
@@ -41,7 +42,7 @@ var kernelParemeter = []string{
 	"CONFIG_CRYPTO_USER_API_HASH", "CONFIG_CGROUPS", "CONFIG_CGROUP_BPF",
 }
 
-func newCommonChecks(_ string, s ssh.Interface) []Checker {
+func newCommonChecks(_ string, s ssh.Interface, modules []string) []Checker {
 	var checks []Checker
 	//if c.TargetCluster.Spec.Features.EnableCilium {
 	//	checks = append(checks, []Checker{
@@ -58,8 +59,6 @@ func newCommonChecks(_ string, s ssh.Interface) []Checker {
 		CPUArchCeck{Interface: s, Arch: 64},
 		KernelCheck{Interface: s, MinKernelVersion: 3, MinMajorVersion: 10},
 
-		KernelModuleCheck{Interface: s, Module: "iptable_nat"},
-
 		FileContentCheck{Interface: s, Path: ipv4Forward, Content: []byte{'1'}},
 
 		FileAvailableCheck{Interface: s, Path: constants.KubectlConfigFile},
@@ -71,13 +70,18 @@ func newCommonChecks(_ string, s ssh.Interface) []Checker {
 		PortOpenCheck{Interface: s, port: constants.ProxyStatusPort},
 		PortOpenCheck{Interface: s, port: constants.KubeletPort},
 	}...)
+	for _, module := range modules {
+		checks = append(checks, KernelModuleCheck{Interface: s, Module: module, Loaded: true})
+	}
 	return checks
 }
 
-// RunMasterChecks checks for master
-func RunMasterChecks(c string, s ssh.Interface) error {
-	checks := newCommonChecks(c, s)
+// RunMasterChecks checks for master, the kernel modules must be loaded and the
+// packages of the release of the version must be in the store.
+func RunMasterChecks(c string, s ssh.Interface, modules []string, version string, release *res.Release) error {
+	checks := newCommonChecks(c, s, modules)
 	checks = append(checks, []Checker{
+		PackagesCheck{Interface: s, Version: version, Release: release},
 		NumCPUCheck{Interface: s, NumCPU: constants.MinNumCPU},
 		DirAvailableCheck{Interface: s, Path: constants.EtcdDataDir},
 		PortOpenCheck{Interface: s, port: 6443}, // kube-apiserver
@@ -96,10 +100,13 @@ func RunMasterChecks(c string, s ssh.Interface) error {
 	return RunChecks(checks)
 }
 
-// RunNodeChecks checks for node
-func RunNodeChecks(c *typesv1.Cluster, s ssh.Interface) error {
-	checks := newCommonChecks(c.ClusterName, s)
-	checks = append(checks, []Checker{}...)
+// RunNodeChecks checks for node, the kernel modules must be loaded and the packages
+// of the release of the cluster version must be in the store.
+func RunNodeChecks(c *typesv1.Cluster, s ssh.Interface, modules []string, release *res.Release) error {
+	checks := newCommonChecks(c.ClusterName, s, modules)
+	checks = append(checks, []Checker{
+		PackagesCheck{Interface: s, Version: c.K8sVersionsWithV, Release: release},
+	}...)
 
 	for _, tool := range tools {
 		checks = append(checks, InPathCheck{Interface: s, executable: tool})
@@ -108,6 +115,12 @@ func RunNodeChecks(c *typesv1.Cluster, s ssh.Interface) error {
 	return RunChecks(checks)
 }
 
+// RunUpgradeChecks checks for a master to upgrade, the packages of the release of the
+// version it is upgraded to must be in the store.
+func RunUpgradeChecks(s ssh.Interface, version string, release *res.Release) error {
+	return RunChecks([]Checker{PackagesCheck{Interface: s, Version: version, Release: release}})
+}
+
 // RunChecks runs each check, displays it's warnings/errors, and once all
 // are processed will exit if any errors occurred.
 func RunChecks(checks []Checker) error {
@@ -463,6 +476,8 @@ func (kc KernelCheck) Check() (warnings, errorList []error) {
 type KernelModuleCheck struct {
 	ssh.Interface
 	Module string
+	// Loaded requires the module to be loaded as well.
+	Loaded bool
 	Label  string
 }
 
@@ -479,7 +494,44 @@ func (kmc KernelModuleCheck) Check() (warnings, errorList []error) {
 	_, _, exit, err := kmc.Execf("modinfo %s", kmc.Module)
 	if err != nil || exit != 0 {
 		errorList = append(errorList, errors.Errorf("%s is required", kmc.Module))
+		return nil, errorList
+	}
+	if kmc.Loaded {
+		// built-in modules show up in /sys/module as well.
+		_, _, exit, err = kmc.Execf("test -d /sys/module/%s", kmc.Module)
+		if err != nil || exit != 0 {
+			errorList = append(errorList, errors.Errorf("%s is not loaded", kmc.Module))
+		}
 	}
 
 	return nil, errorList
 }
+
+// PackagesCheck checks the store has the kubernetes node package of the version and
+// the packages of its release for the arch of the node.
+type PackagesCheck struct {
+	ssh.Interface
+	Version string
+	Release *res.Release
+}
+
+// Name returns the label for PackagesCheck
+func (PackagesCheck) Name() string {
+	return "Packages"
+}
+
+// Check validates the packages are in the store.
+func (pc PackagesCheck) Check() (warnings, errorList []error) {
+	arch := res.Arch(pc.Interface)
+	if arch == "" {
+		errorList = append(errorList, errors.New("unsupported arch of the node"))
+		return
+	}
+	if _, err := res.KubernetesNode.Resource(arch, pc.Version); err != nil {
+		errorList = append(errorList, errors.Wrapf(err, "kubernetes %s for %s is not in the store", pc.Version, arch))
+	}
+	if _, err := pc.Release.Versions(arch); err != nil {
+		errorList = append(errorList, err)
+	}
+	return warnings, errorList
+}
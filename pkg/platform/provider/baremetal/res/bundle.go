@@ -0,0 +1,287 @@
+package res
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// verified remembers the files whose checksum matched, keyed by path, size and
+// modification time, so that the tarballs are hashed once instead of on each copy.
+var verified sync.Map
+
+type verifiedKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+	sha256  string
+}
+
+// Import loads the bundle tarball into the store. The tarballs of the bundle are checked
+// against the sums of its manifest before any of them is added to the store.
+func Import(bundle string, storeDir string) ([]Artifact, error) {
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		return nil, err
+	}
+	// extract into the store dir, so that the tarballs are only renamed afterwards.
+	tmpDir, err := ioutil.TempDir(storeDir, ".import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extract(bundle, tmpDir); err != nil {
+		return nil, fmt.Errorf("extract %s error: %w", bundle, err)
+	}
+	m, err := LoadManifest(filepath.Join(tmpDir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	compatibility, err := LoadCompatibility(filepath.Join(tmpDir, CompatibilityFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Artifacts) == 0 && len(compatibility.Releases) == 0 {
+		return nil, fmt.Errorf("bundle %s has no artifacts", bundle)
+	}
+	for _, a := range m.Artifacts {
+		if a.Name == "" || a.Arch == "" || a.Version == "" || a.SHA256 == "" {
+			return nil, fmt.Errorf("artifact %q of bundle %s is incomplete", a.File, bundle)
+		}
+		file, err := inside(tmpDir, a.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := Verify(file, a.SHA256); err != nil {
+			return nil, err
+		}
+	}
+
+	store, err := LoadManifest(filepath.Join(storeDir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var imported []Artifact
+	for _, a := range m.Artifacts {
+		dst := artifactPath(a.Name, a.Arch, a.Version)
+		if err := os.MkdirAll(filepath.Join(storeDir, filepath.Dir(dst)), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(filepath.Join(tmpDir, a.File), filepath.Join(storeDir, dst)); err != nil {
+			return nil, err
+		}
+		a.File = dst
+		store.Put(a)
+		imported = append(imported, a)
+	}
+	if err := store.Save(filepath.Join(storeDir, ManifestFile)); err != nil {
+		return nil, err
+	}
+	if len(compatibility.Releases) > 0 {
+		if err := mergeCompatibility(storeDir, compatibility); err != nil {
+			return nil, err
+		}
+	}
+
+	return imported, nil
+}
+
+// Seed adds the tarballs of the dir laid out like the store, e.g. the tarballs built
+// into the image of the manager, to the store. The tarballs are trusted as they are, their
+// sums are taken while they are copied, and the artifacts in the store already are kept.
+func Seed(srcDir string, storeDir string) ([]Artifact, error) {
+	files, err := filepath.Glob(filepath.Join(srcDir, "linux-*", "*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+	store, err := LoadManifest(filepath.Join(storeDir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var seeded []Artifact
+	for _, file := range files {
+		arch := strings.TrimPrefix(filepath.Base(filepath.Dir(file)), "linux-")
+		name, version, ok := parseArtifactFile(filepath.Base(file), arch)
+		if !ok || store.Find(name, arch, version) != nil {
+			continue
+		}
+		a := Artifact{Name: name, Version: version, Arch: arch, File: artifactPath(name, arch, version)}
+		if a.SHA256, err = copyFile(file, filepath.Join(storeDir, a.File)); err != nil {
+			return nil, fmt.Errorf("seed %s error: %w", file, err)
+		}
+		store.Put(a)
+		seeded = append(seeded, a)
+	}
+	if len(seeded) > 0 {
+		if err := store.Save(filepath.Join(storeDir, ManifestFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	return seeded, nil
+}
+
+// parseArtifactFile returns the name and the version of the tarball named like
+// <name>-linux-<arch>-<version>.tar.gz.
+func parseArtifactFile(basename string, arch string) (string, string, bool) {
+	sep := fmt.Sprintf("-linux-%s-", arch)
+	i := strings.Index(basename, sep)
+	if i <= 0 || !strings.HasSuffix(basename, ".tar.gz") {
+		return "", "", false
+	}
+	version := strings.TrimSuffix(basename[i+len(sep):], ".tar.gz")
+	if version == "" {
+		return "", "", false
+	}
+
+	return basename[:i], version, true
+}
+
+// copyFile copies the file by a temporary file renamed at once, and returns its sum.
+func copyFile(src string, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), os.Rename(tmp, dst)
+}
+
+// mergeCompatibility merges the compatibility matrix of a bundle into the one of the store.
+func mergeCompatibility(storeDir string, c *Compatibility) error {
+	store, err := LoadCompatibility(filepath.Join(storeDir, CompatibilityFile))
+	if err != nil {
+		return err
+	}
+	store.Merge(c)
+
+	return store.Save(filepath.Join(storeDir, CompatibilityFile))
+}
+
+// Available returns the versions in the store of each package for each arch.
+func Available(storeDir string) (map[string]map[string][]string, error) {
+	m, err := LoadManifest(filepath.Join(storeDir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]map[string][]string)
+	for _, a := range m.Artifacts {
+		if _, ok := available[a.Name]; !ok {
+			available[a.Name] = m.Versions(a.Name)
+		}
+	}
+
+	return available, nil
+}
+
+// Verify checks the SHA-256 sum of the file.
+func Verify(file string, sum string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	key := verifiedKey{path: file, size: info.Size(), modTime: info.ModTime(), sha256: sum}
+	if _, ok := verified.Load(key); ok {
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, sum) {
+		return fmt.Errorf("checksum of %s mismatch: expected %s, got %s", file, sum, actual)
+	}
+	verified.Store(key, struct{}{})
+
+	return nil
+}
+
+func extract(bundle string, dir string) error {
+	f, err := os.Open(bundle)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := inside(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// inside joins the name to the dir, the names escaping the dir are refused.
+func inside(dir, name string) (string, error) {
+	cleaned := filepath.Clean(name)
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("illegal path %q in bundle", name)
+	}
+
+	return filepath.Join(dir, cleaned), nil
+}
@@ -0,0 +1,155 @@
+package res
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+type bundleEntry struct {
+	name string
+	data []byte
+}
+
+// writeBundle writes the entries as a bundle tarball and returns its path.
+func writeBundle(t *testing.T, entries ...bundleEntry) string {
+	file := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(file)
+	require.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(e.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return file
+}
+
+func manifestEntry(t *testing.T, artifacts ...Artifact) bundleEntry {
+	data, err := yaml.Marshal(&Manifest{Artifacts: artifacts})
+	require.NoError(t, err)
+	return bundleEntry{name: ManifestFile, data: data}
+}
+
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func TestImport(t *testing.T) {
+	tarball := []byte("kubernetes-node")
+	artifact := Artifact{Name: "kubernetes-node", Version: "v1.21.5", Arch: "amd64", File: "kubernetes-node.tar.gz", SHA256: sum(tarball)}
+	bundle := writeBundle(t, manifestEntry(t, artifact), bundleEntry{name: artifact.File, data: tarball})
+
+	storeDir := t.TempDir()
+	imported, err := Import(bundle, storeDir)
+	require.NoError(t, err)
+	require.Len(t, imported, 1)
+	assert.Equal(t, artifactPath("kubernetes-node", "amd64", "v1.21.5"), imported[0].File)
+
+	data, err := ioutil.ReadFile(filepath.Join(storeDir, imported[0].File))
+	require.NoError(t, err)
+	assert.Equal(t, tarball, data)
+	store, err := LoadManifest(filepath.Join(storeDir, ManifestFile))
+	require.NoError(t, err)
+	assert.Equal(t, imported, store.Artifacts)
+}
+
+func TestImportEscapingPath(t *testing.T) {
+	storeDir := t.TempDir()
+	// the bundle is extracted into a temporary dir of the store.
+	bundle := writeBundle(t, bundleEntry{name: "../escaped", data: []byte("evil")})
+
+	_, err := Import(bundle, storeDir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(storeDir, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestImportCorruptedArtifact(t *testing.T) {
+	artifact := Artifact{Name: "docker", Version: "20.10.7", Arch: "amd64", File: "docker.tar.gz", SHA256: sum([]byte("docker"))}
+	bundle := writeBundle(t, manifestEntry(t, artifact), bundleEntry{name: artifact.File, data: []byte("corrupted")})
+
+	storeDir := t.TempDir()
+	_, err := Import(bundle, storeDir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(storeDir, ManifestFile))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInside(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"linux-amd64/docker.tar.gz", "/store/linux-amd64/docker.tar.gz", false},
+		{"./a/../b", "/store/b", false},
+		{"..", "", true},
+		{"../etc/passwd", "", true},
+		{"a/../../etc/passwd", "", true},
+		{"/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		got, err := inside("/store", tt.name)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "docker.tar.gz")
+	require.NoError(t, ioutil.WriteFile(file, []byte("docker"), 0644))
+
+	assert.Error(t, Verify(file, sum([]byte("other"))))
+	require.NoError(t, Verify(file, sum([]byte("docker"))))
+
+	// the sum is remembered as long as the size and the modification time are the same.
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(file, []byte("dockef"), 0644))
+	require.NoError(t, os.Chtimes(file, info.ModTime(), info.ModTime()))
+	assert.NoError(t, Verify(file, sum([]byte("docker"))))
+
+	modTime := info.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+	assert.Error(t, Verify(file, sum([]byte("docker"))))
+}
+
+func TestManifestPut(t *testing.T) {
+	m := new(Manifest)
+	m.Put(Artifact{Name: "docker", Version: "20.10.7", Arch: "amd64", SHA256: "a"})
+	m.Put(Artifact{Name: "cni-plugins", Version: "v0.8.6", Arch: "amd64", SHA256: "b"})
+	m.Put(Artifact{Name: "docker", Version: "19.03.14", Arch: "amd64", SHA256: "c"})
+	m.Put(Artifact{Name: "docker", Version: "20.10.7", Arch: "amd64", SHA256: "d"})
+
+	require.Len(t, m.Artifacts, 3)
+	assert.Equal(t, "cni-plugins", m.Artifacts[0].Name)
+	assert.Equal(t, "19.03.14", m.Artifacts[1].Version)
+	assert.Equal(t, "20.10.7", m.Artifacts[2].Version)
+
+	a := m.Find("docker", "amd64", "20.10.7")
+	require.NotNil(t, a)
+	assert.Equal(t, "d", a.SHA256)
+	assert.Nil(t, m.Find("docker", "arm64", "20.10.7"))
+	assert.Nil(t, m.Find("docker", "amd64", "18.09.9"))
+}
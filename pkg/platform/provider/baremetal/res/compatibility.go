@@ -0,0 +1,214 @@
+package res
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"gopkg.in/yaml.v2"
+	"pml.io/april/pkg/platform/provider/baremetal/constants"
+	"pml.io/april/pkg/spec"
+)
+
+// CompatibilityFile lists the package versions going with each kubernetes minor, a
+// bundle carries it next to its manifest and the ConfigMap of the matrix under this key.
+const CompatibilityFile = "compatibility.yaml"
+
+// Compatibility is the matrix of the package versions going with each kubernetes minor.
+type Compatibility struct {
+	Releases []Release `yaml:"releases"`
+}
+
+// Release lists the versions of the packages going with a kubernetes minor, the first
+// version of a package found in the store is installed.
+type Release struct {
+	// Minor is the kubernetes minor, e.g. "1.22".
+	Minor string `yaml:"minor"`
+	// Packages are the versions keyed by the package name, e.g. "docker".
+	Packages map[string][]string `yaml:"packages"`
+}
+
+// DefaultCompatibility is the matrix of the versions the manager is built with, every
+// kubernetes minor of spec goes with all of them.
+func DefaultCompatibility() *Compatibility {
+	c := new(Compatibility)
+	for _, version := range spec.K8sVersions {
+		minor, err := minorOf(version)
+		if err != nil || c.Release(minor) != nil {
+			continue
+		}
+		c.Releases = append(c.Releases, Release{
+			Minor: minor,
+			Packages: map[string][]string{
+				Docker.Name:         spec.DockerVersions,
+				CNIPlugins.Name:     spec.CNIPluginsVersions,
+				ConntrackTools.Name: spec.ConntrackToolsVersions,
+			},
+		})
+	}
+
+	return c
+}
+
+// ParseCompatibility parses the matrix, the minors must be valid.
+func ParseCompatibility(data []byte) (*Compatibility, error) {
+	c := new(Compatibility)
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	for i := range c.Releases {
+		minor, err := minorOf(c.Releases[i].Minor)
+		if err != nil {
+			return nil, err
+		}
+		c.Releases[i].Minor = minor
+	}
+
+	return c, nil
+}
+
+// LoadCompatibility reads the matrix file, a missing file is an empty matrix.
+func LoadCompatibility(file string) (*Compatibility, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return new(Compatibility), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseCompatibility(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s error: %w", file, err)
+	}
+
+	return c, nil
+}
+
+// StoreCompatibility returns the built-in matrix overridden by the one imported with
+// the bundles.
+func StoreCompatibility() (*Compatibility, error) {
+	imported, err := LoadCompatibility(path.Join(constants.SrcDir, CompatibilityFile))
+	if err != nil {
+		return nil, err
+	}
+	c := DefaultCompatibility()
+	c.Merge(imported)
+
+	return c, nil
+}
+
+// Save writes the matrix file at once, the readers never see a partial matrix.
+func (c *Compatibility) Save(file string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// Merge adds the releases of other to the matrix, replacing the ones of the same minor.
+func (c *Compatibility) Merge(other *Compatibility) {
+	for _, release := range other.Releases {
+		if r := c.Release(release.Minor); r != nil {
+			*r = release
+			continue
+		}
+		c.Releases = append(c.Releases, release)
+	}
+	sort.Slice(c.Releases, func(i, j int) bool {
+		a, errA := semver.NewVersion(c.Releases[i].Minor)
+		b, errB := semver.NewVersion(c.Releases[j].Minor)
+		if errA != nil || errB != nil {
+			return c.Releases[i].Minor < c.Releases[j].Minor
+		}
+		return a.LessThan(b)
+	})
+}
+
+// Release returns the release of the minor, nil if the matrix has none.
+func (c *Compatibility) Release(minor string) *Release {
+	for i := range c.Releases {
+		if c.Releases[i].Minor == minor {
+			return &c.Releases[i]
+		}
+	}
+
+	return nil
+}
+
+// ReleaseOf returns the release going with the kubernetes version.
+func (c *Compatibility) ReleaseOf(version string) (*Release, error) {
+	minor, err := minorOf(version)
+	if err != nil {
+		return nil, err
+	}
+	r := c.Release(minor)
+	if r == nil {
+		return nil, fmt.Errorf("kubernetes %s is not in the compatibility matrix", version)
+	}
+
+	return r, nil
+}
+
+// Versions returns the version of each package of the release which is in the store
+// for the arch, the packages without any version in the store are reported together.
+func (r *Release) Versions(arch string) (map[string]string, error) {
+	versions := make(map[string]string, len(r.Packages))
+	var missing []string
+	for name, candidates := range r.Packages {
+		version, err := available(name, candidates, arch)
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("%s %v", name, candidates))
+			continue
+		}
+		versions[name] = version
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("no package of kubernetes %s for %s in the store: %s", r.Minor, arch, strings.Join(missing, ", "))
+	}
+
+	return versions, nil
+}
+
+// Version returns the version of the package to install for the arch, the default
+// version of the package if the release doesn't list it.
+func (r *Release) Version(p *Package, arch string) (string, error) {
+	candidates, ok := r.Packages[p.Name]
+	if !ok {
+		return p.DefaultVersion(), nil
+	}
+
+	return available(p.Name, candidates, arch)
+}
+
+// available returns the first of the versions of the package which is in the store.
+func available(name string, versions []string, arch string) (string, error) {
+	p := Package{Name: name, Versions: versions}
+	for _, version := range versions {
+		if _, err := p.Resource(arch, version); err == nil {
+			return version, nil
+		}
+	}
+
+	return "", fmt.Errorf("no version of %s %v for %s in the store", name, versions, arch)
+}
+
+// minorOf returns the minor of the kubernetes version, e.g. "1.22" for "v1.22.4".
+func minorOf(version string) (string, error) {
+	v, err := semver.NewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return "", fmt.Errorf("invalid kubernetes version %q: %w", version, err)
+	}
+
+	return fmt.Sprintf("%d.%d", v.Major(), v.Minor()), nil
+}
@@ -0,0 +1,108 @@
+package res
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFile lists the artifacts of a bundle, and of the store the bundles are
+// imported into.
+const ManifestFile = "manifest.yaml"
+
+type Manifest struct {
+	Artifacts []Artifact `yaml:"artifacts"`
+}
+
+// Artifact is a package tarball of a version for an arch.
+type Artifact struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Arch    string `yaml:"arch"`
+	// File is the path of the tarball relative to the manifest.
+	File   string `yaml:"file"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// LoadManifest reads the manifest file, a missing file is an empty manifest.
+func LoadManifest(file string) (*Manifest, error) {
+	m := new(Manifest)
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse %s error: %w", file, err)
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest file at once, the readers never see a partial manifest.
+func (m *Manifest) Save(file string) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+func (m *Manifest) Find(name, arch, version string) *Artifact {
+	for i := range m.Artifacts {
+		a := &m.Artifacts[i]
+		if a.Name == name && a.Arch == arch && a.Version == version {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// Put adds the artifact to the manifest, replacing the one of the same name, arch
+// and version.
+func (m *Manifest) Put(artifact Artifact) {
+	if a := m.Find(artifact.Name, artifact.Arch, artifact.Version); a != nil {
+		*a = artifact
+		return
+	}
+	m.Artifacts = append(m.Artifacts, artifact)
+	sort.Slice(m.Artifacts, func(i, j int) bool {
+		a, b := m.Artifacts[i], m.Artifacts[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Arch != b.Arch {
+			return a.Arch < b.Arch
+		}
+		return a.Version < b.Version
+	})
+}
+
+// Versions returns the versions of the package for each arch.
+func (m *Manifest) Versions(name string) map[string][]string {
+	versions := make(map[string][]string)
+	for _, a := range m.Artifacts {
+		if a.Name == name {
+			versions[a.Arch] = append(versions[a.Arch], a.Version)
+		}
+	}
+
+	return versions
+}
+
+// artifactPath is where the tarball of the artifact is put in the store.
+func artifactPath(name, arch, version string) string {
+	return filepath.Join(fmt.Sprintf("linux-%s", arch), fmt.Sprintf("%s-linux-%s-%s.tar.gz", name, arch, version))
+}
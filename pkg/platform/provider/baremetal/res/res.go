@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"pml.io/april/pkg/platform/provider/baremetal/constants"
 	"pml.io/april/pkg/spec"
+	"pml.io/april/pkg/util/log"
 	"pml.io/april/pkg/util/ssh"
 	"strings"
 
@@ -32,6 +33,12 @@ var (
 		Name:     "kubernetes-node",
 		Versions: spec.K8sVersionsWithV,
 	}
+	// KubernetesImages is the images of a kubernetes version saved by docker save,
+	// nodes without access to the registry load them instead of pulling.
+	KubernetesImages = Package{
+		Name:     "kubernetes-images",
+		Versions: spec.K8sVersionsWithV,
+	}
 	NvidiaDriver = Package{
 		Name:     "NVIDIA",
 		Versions: spec.NvidiaDriverVersions,
@@ -80,13 +87,22 @@ func (p *Package) CopyToNodeWithDefault(s ssh.Interface) (string, error) {
 
 // CopyToNode copy package which use default version to node and return dst filename
 func (p *Package) CopyToNode(s ssh.Interface, version string) (string, error) {
-	srcFile, err := p.ResourceForNode(s, version)
+	arch := Arch(s)
+	srcFile, artifact, err := p.artifact(arch, version)
 	if err != nil {
 		return "", err
 	}
 	dstFile := path.Join(constants.DstTmpDir, filepath.Base(srcFile))
 
-	err = s.CopyFile(srcFile, dstFile)
+	// the node downloads the tarball itself if it can, so that many nodes are not fed
+	// one by one through the manager.
+	if err := download(s, artifact, dstFile); err == nil {
+		return dstFile, nil
+	} else if artifactServer.URL != "" {
+		log.Infof("Download %s on the node error, upload it instead: %v", artifact.File, err)
+	}
+
+	err = s.UploadFile(srcFile, dstFile)
 	if err != nil {
 		return "", err
 	}
@@ -98,16 +114,34 @@ func (p *Package) ResourceForNode(s ssh.Interface, version string) (string, erro
 }
 
 func (p *Package) Resource(arch, version string) (string, error) {
+	srcFile, _, err := p.artifact(arch, version)
+	return srcFile, err
+}
+
+// artifact returns the tarball of the version in the store along with its artifact
+// in the manifest, a tarball is never handed out unchecked.
+func (p *Package) artifact(arch, version string) (string, *Artifact, error) {
 	version, err := p.NormalizeVersion(version)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	basename := fmt.Sprintf("linux-%s/%s-linux-%s-%s.tar.gz", arch, p.Name, arch, version)
+	basename := artifactPath(p.Name, arch, version)
 	srcFile := path.Join(constants.SrcDir, basename)
 	if _, err := os.Stat(srcFile); err != nil {
-		return "", err
+		return "", nil, err
+	}
+	m, err := LoadManifest(path.Join(constants.SrcDir, ManifestFile))
+	if err != nil {
+		return "", nil, err
 	}
-	return srcFile, nil
+	artifact := m.Find(p.Name, arch, version)
+	if artifact == nil {
+		return "", nil, fmt.Errorf("%s is not in the manifest of %s", basename, constants.SrcDir)
+	}
+	if err := Verify(srcFile, artifact.SHA256); err != nil {
+		return "", nil, err
+	}
+	return srcFile, artifact, nil
 }
 
 func (p *Package) DefaultVersion() string {
@@ -121,15 +155,25 @@ func (p *Package) NormalizeVersion(version string) (string, error) {
 		version = version[1:]
 	}
 
-	if p.Name == "kubeadm" || p.Name == "kubernetes-node" {
+	// the kubernetes packages go by the version of the cluster, which may carry the
+	// suffix of a distribution like v1.20.11-eks-1; the manifest still has to list them.
+	if p.Name == "kubeadm" || p.Name == "kubernetes-node" || p.Name == "kubernetes-images" {
 		return version, nil
 	}
 
 	if funk.ContainsString(p.Versions, version) {
 		return version, nil
 	}
+	// the versions imported with a bundle are valid as well.
+	if m, err := LoadManifest(path.Join(constants.SrcDir, ManifestFile)); err == nil {
+		for _, versions := range m.Versions(p.Name) {
+			if funk.ContainsString(versions, version) {
+				return version, nil
+			}
+		}
+	}
 
-	return "", errors.New("invalid version")
+	return "", fmt.Errorf("invalid version %s of %s", version, p.Name)
 }
 
 func Arch(s ssh.Interface) string {
@@ -0,0 +1,85 @@
+package res
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"pml.io/april/pkg/util/ssh"
+)
+
+// artifactServer is where the nodes download the tarballs from. The tarballs are
+// copied over SFTP when it is not set, or not reachable from a node.
+var artifactServer struct {
+	URL   string
+	Token string
+}
+
+// SetArtifactServer sets the URL the nodes reach the server returned by NewServer at,
+// and the token they authenticate with.
+func SetArtifactServer(url, token string) {
+	artifactServer.URL = strings.TrimSuffix(url, "/")
+	artifactServer.Token = token
+}
+
+// NewServer serves the tarballs of the store to the bearers of the token. Range
+// requests are supported, so that an interrupted download is resumed.
+func NewServer(storeDir, token string) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		name, err := inside(storeDir, strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
+
+// download fetches the tarball of the artifact from the artifact server onto the node,
+// a partial file left by a previous try is resumed.
+func download(s ssh.Interface, artifact *Artifact, dst string) error {
+	if artifactServer.URL == "" {
+		return fmt.Errorf("no artifact server")
+	}
+	check := fmt.Sprintf("echo '%s  %s' | sha256sum -c --status -", artifact.SHA256, dst)
+	if _, err := s.CombinedOutput(check); err == nil {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/%s", artifactServer.URL, artifact.File)
+	cmd := fmt.Sprintf("mkdir -p $(dirname %s) && curl -fsS --retry 3 --connect-timeout 10 -C - -H 'Authorization: Bearer %s' -o %s %s",
+		dst, artifactServer.Token, dst, url)
+	if _, err := s.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("download %s error: %w", url, err)
+	}
+	if _, err := s.CombinedOutput(check); err != nil {
+		// a corrupt file must not be resumed.
+		_, _ = s.CombinedOutput(fmt.Sprintf("rm -f %s", dst))
+		return fmt.Errorf("checksum of %s mismatch", dst)
+	}
+
+	return nil
+}
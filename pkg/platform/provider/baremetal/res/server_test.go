@@ -0,0 +1,63 @@
+package res
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	root := t.TempDir()
+	storeDir := filepath.Join(root, "store")
+	require.NoError(t, os.MkdirAll(filepath.Join(storeDir, "linux-amd64"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(storeDir, "linux-amd64", "docker.tar.gz"), []byte("0123456789"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0644))
+	server := NewServer(storeDir, "token")
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		rangeHeader   string
+		wantStatus    int
+		wantBody      string
+	}{
+		{"download", http.MethodGet, "/linux-amd64/docker.tar.gz", "Bearer token", "", http.StatusOK, "0123456789"},
+		{"resume", http.MethodGet, "/linux-amd64/docker.tar.gz", "Bearer token", "bytes=4-", http.StatusPartialContent, "456789"},
+		{"range", http.MethodGet, "/linux-amd64/docker.tar.gz", "Bearer token", "bytes=2-5", http.StatusPartialContent, "2345"},
+		{"unsatisfiable range", http.MethodGet, "/linux-amd64/docker.tar.gz", "Bearer token", "bytes=20-", http.StatusRequestedRangeNotSatisfiable, ""},
+		{"no token", http.MethodGet, "/linux-amd64/docker.tar.gz", "", "", http.StatusUnauthorized, ""},
+		{"wrong token", http.MethodGet, "/linux-amd64/docker.tar.gz", "Bearer other", "", http.StatusUnauthorized, ""},
+		{"not a bearer", http.MethodGet, "/linux-amd64/docker.tar.gz", "token", "", http.StatusUnauthorized, ""},
+		{"method", http.MethodPut, "/linux-amd64/docker.tar.gz", "Bearer token", "", http.StatusMethodNotAllowed, ""},
+		{"escaping path", http.MethodGet, "/../secret", "Bearer token", "", http.StatusBadRequest, ""},
+		{"escaping nested path", http.MethodGet, "/linux-amd64/../../secret", "Bearer token", "", http.StatusBadRequest, ""},
+		{"missing", http.MethodGet, "/linux-amd64/conntrack.tar.gz", "Bearer token", "", http.StatusNotFound, ""},
+		{"dir", http.MethodGet, "/linux-amd64", "Bearer token", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://artifacts"+tt.path, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.rangeHeader != "" {
+				r.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
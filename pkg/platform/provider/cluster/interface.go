@@ -41,9 +41,23 @@ const (
 	ReasonFailedUpdate = "FailedUpdate"
 	ReasonFailedDelete = "FailedDelete"
 
+	ReasonFailedUpgrade   = "FailedUpgrade"
+	ReasonFailedScaleUp   = "FailedScaleUp"
+	ReasonFailedScaleDown = "FailedScaleDown"
+
 	ConditionTypeDone = "EnsureDone"
 )
 
+// The pipelines whose steps are recorded in the conditions of the cluster. The type of
+// the condition of a step is prefixed with its pipeline, as a handler may be a step of
+// several pipelines.
+const (
+	PipelineCreate    = "Create"
+	PipelineUpgrade   = "Upgrade"
+	PipelineScaleUp   = "ScaleUp"
+	PipelineScaleDown = "ScaleDown"
+)
+
 type APIProvider interface {
 	RegisterHandler(mux *mux.PathRecorderMux)
 	Validate(cluster *types.Cluster) field.ErrorList
@@ -151,16 +165,119 @@ func (p *DelegateProvider) AfterCreate(cluster *types.Cluster) error {
 }
 
 func (p *DelegateProvider) OnCreate(ctx context.Context, cluster *types.Cluster) error {
-	condition, err := p.getCurrentCondition(cluster.TargetCluster, v1alpha1.ClusterInitializing, p.CreateHandlers)
+	return p.runPipeline(ctx, PipelineCreate, cluster, v1alpha1.ClusterInitializing, p.CreateHandlers, ReasonFailedInit, func() error {
+		cluster.TargetCluster.Status.Phase = v1alpha1.ClusterRunning
+		cluster.TargetCluster.Status.Version = cluster.TargetCluster.Spec.Version
+		if cluster.TargetCluster.Spec.MachineCount != nil {
+			cluster.TargetCluster.Status.MachineCount = *cluster.TargetCluster.Spec.MachineCount
+		}
+		if err := p.OnRunning(ctx, cluster); err != nil {
+			return fmt.Errorf("%s.OnRunning error: %w", p.Name(), err)
+		}
+		return nil
+	})
+}
+
+// OnUpdate runs one step of the upgrade or scale pipeline the cluster is in. A running
+// cluster whose spec differs from its status is moved to the matching phase, otherwise
+// the update handlers are run.
+func (p *DelegateProvider) OnUpdate(ctx context.Context, cluster *types.Cluster) error {
+	c := cluster.TargetCluster
+	switch c.Status.Phase {
+	case v1alpha1.ClusterUpgrading:
+		return p.runPipeline(ctx, PipelineUpgrade, cluster, v1alpha1.ClusterUpgrading, p.UpgradeHandlers, ReasonFailedUpgrade, func() error {
+			c.Status.Phase = v1alpha1.ClusterRunning
+			c.Status.Version = c.Spec.Version
+			return nil
+		})
+	case v1alpha1.ClusterUpscaling:
+		return p.runPipeline(ctx, PipelineScaleUp, cluster, v1alpha1.ClusterUpscaling, p.ScaleUpHandlers, ReasonFailedScaleUp, func() error {
+			c.Status.Phase = v1alpha1.ClusterRunning
+			if c.Spec.MachineCount != nil {
+				c.Status.MachineCount = *c.Spec.MachineCount
+			}
+			return nil
+		})
+	case v1alpha1.ClusterDownscaling:
+		return p.runPipeline(ctx, PipelineScaleDown, cluster, v1alpha1.ClusterDownscaling, p.ScaleDownHandlers, ReasonFailedScaleDown, func() error {
+			c.Status.Phase = v1alpha1.ClusterRunning
+			if c.Spec.MachineCount != nil {
+				c.Status.MachineCount = *c.Spec.MachineCount
+			}
+			return nil
+		})
+	case v1alpha1.ClusterRunning:
+	default:
+		return nil
+	}
+
+	if phase := p.desiredPhase(c); phase != c.Status.Phase {
+		log.FromContext(ctx).Info("cluster spec changed", "phase", phase)
+		c.Status.Phase = phase
+		return nil
+	}
+
+	for _, handler := range p.UpdateHandlers {
+		ctx := log.FromContext(ctx).WithName("ClusterProvider.OnUpdate").WithName(handler.Name()).WithContext(ctx)
+		log.FromContext(ctx).Info("Doing")
+		startTime := time.Now()
+		err := handler(ctx, cluster)
+		log.FromContext(ctx).Info("Done", "error", err, "cost", time.Since(startTime).String())
+		if err != nil {
+			c.Status.Reason = ReasonFailedUpdate
+			c.Status.Message = fmt.Sprintf("%s error: %v", handler.Name(), err)
+			return err
+		}
+	}
+	c.Status.Reason = ""
+	c.Status.Message = ""
+
+	return nil
+}
+
+func (p *DelegateProvider) OnDelete(ctx context.Context, cluster *types.Cluster) error {
+	for _, handler := range p.DeleteHandlers {
+		ctx := log.FromContext(ctx).WithName("ClusterProvider.OnDelete").WithName(handler.Name()).WithContext(ctx)
+		log.FromContext(ctx).Info("Doing")
+		startTime := time.Now()
+		err := handler(ctx, cluster)
+		log.FromContext(ctx).Info("Done", "error", err, "cost", time.Since(startTime).String())
+		if err != nil {
+			cluster.TargetCluster.Status.Reason = ReasonFailedDelete
+			cluster.TargetCluster.Status.Message = fmt.Sprintf("%s error: %v", handler.Name(), err)
+			return err
+		}
+	}
+	cluster.TargetCluster.Status.Reason = ""
+	cluster.TargetCluster.Status.Message = ""
+
+	return nil
+}
+
+func (p *DelegateProvider) OnRunning(ctx context.Context, cluster *types.Cluster) error {
+	return nil
+}
+
+func (p *DelegateProvider) OnFilter(ctx context.Context, cluster *types.Cluster) (pass bool) {
+	return true
+}
+
+// runPipeline runs the handler of the current condition of the phase, and calls done
+// once the last handler succeeded. A failed handler is recorded in its condition and
+// its error returned, so that the cluster is requeued with a backoff and the handler
+// retried then.
+func (p *DelegateProvider) runPipeline(ctx context.Context, pipeline string, cluster *types.Cluster,
+	phase v1alpha1.ClusterPhase, handlers []Handler, failedReason string, done func() error) error {
+	condition, err := p.getCurrentCondition(cluster.TargetCluster, phase, pipeline, handlers)
 	if err != nil {
 		return err
 	}
 
-	handler := p.getHandler(condition.Type, p.CreateHandlers)
+	handler := p.getHandler(pipeline, condition.Type, handlers)
 	if handler == nil {
 		return fmt.Errorf("can't get handler by %s", condition.Type)
 	}
-	ctx = log.FromContext(ctx).WithName("ClusterProvider.OnCreate").WithName(handler.Name()).WithContext(ctx)
+	ctx = log.FromContext(ctx).WithName("ClusterProvider.On" + pipeline).WithName(handler.Name()).WithContext(ctx)
 	log.FromContext(ctx).Info("Doing")
 	startTime := time.Now()
 	err = handler(ctx, cluster)
@@ -170,9 +287,9 @@ func (p *DelegateProvider) OnCreate(ctx context.Context, cluster *types.Cluster)
 			Type:    condition.Type,
 			Status:  v1alpha1.ConditionFalse,
 			Message: err.Error(),
-			Reason:  ReasonFailedInit,
+			Reason:  failedReason,
 		}, false)
-		return nil
+		return fmt.Errorf("%s error: %w", handler.Name(), err)
 	}
 
 	cluster.TargetCluster.SetCondition(v1alpha1.ClusterCondition{
@@ -180,61 +297,60 @@ func (p *DelegateProvider) OnCreate(ctx context.Context, cluster *types.Cluster)
 		Status: v1alpha1.ConditionTrue,
 	}, false)
 
-	nextConditionType := p.getNextConditionType(condition.Type, p.CreateHandlers)
+	nextConditionType := p.getNextConditionType(pipeline, condition.Type, handlers)
 	if nextConditionType == ConditionTypeDone {
-		cluster.TargetCluster.Status.Phase = v1alpha1.ClusterRunning
-		if err := p.OnRunning(ctx, cluster); err != nil {
-			return fmt.Errorf("%s.OnRunning error: %w", p.Name(), err)
-		}
-	} else {
-		cluster.TargetCluster.SetCondition(v1alpha1.ClusterCondition{
-			Type:    nextConditionType,
-			Status:  v1alpha1.ConditionUnknown,
-			Message: "waiting execute",
-			Reason:  ReasonWaiting,
-		}, false)
+		return done()
 	}
+	cluster.TargetCluster.SetCondition(v1alpha1.ClusterCondition{
+		Type:    nextConditionType,
+		Status:  v1alpha1.ConditionUnknown,
+		Message: "waiting execute",
+		Reason:  ReasonWaiting,
+	}, false)
 
 	return nil
 }
 
-func (p *DelegateProvider) OnUpdate(ctx context.Context, cluster *types.Cluster) error {
-	return nil
-}
-
-func (p *DelegateProvider) OnDelete(ctx context.Context, cluster *types.Cluster) error {
-	return nil
-}
+// desiredPhase returns the phase a running cluster has to go through to reach its
+// spec. Operations the provider has no handlers for are not started.
+func (p *DelegateProvider) desiredPhase(c *v1alpha1.Cluster) v1alpha1.ClusterPhase {
+	if c.Spec.Version != "" && c.Spec.Version != c.Status.Version && len(p.UpgradeHandlers) > 0 {
+		return v1alpha1.ClusterUpgrading
+	}
+	if c.Spec.MachineCount != nil {
+		desired := *c.Spec.MachineCount
+		if desired > c.Status.MachineCount && len(p.ScaleUpHandlers) > 0 {
+			return v1alpha1.ClusterUpscaling
+		}
+		if desired < c.Status.MachineCount && len(p.ScaleDownHandlers) > 0 {
+			return v1alpha1.ClusterDownscaling
+		}
+	}
 
-func (p *DelegateProvider) OnRunning(ctx context.Context, cluster *types.Cluster) error {
-	return nil
+	return c.Status.Phase
 }
 
-func (p *DelegateProvider) OnFilter(ctx context.Context, cluster *types.Cluster) (pass bool) {
-	return true
+// conditionTypeOf returns the type of the condition of the handler in the pipeline.
+func conditionTypeOf(pipeline string, handler Handler) string {
+	return pipeline + "." + handler.Name()
 }
 
-func (p *DelegateProvider) getNextConditionType(conditionType string, handlers []Handler) string {
-	var (
-		i       int
-		handler Handler
-	)
-	for i, handler = range handlers {
-		if handler.Name() == conditionType {
-			break
+func (p *DelegateProvider) getNextConditionType(pipeline string, conditionType string, handlers []Handler) string {
+	for i, handler := range handlers {
+		if conditionTypeOf(pipeline, handler) == conditionType {
+			if i == len(handlers)-1 {
+				break
+			}
+			return conditionTypeOf(pipeline, handlers[i+1])
 		}
 	}
-	if i == len(handlers)-1 {
-		return ConditionTypeDone
-	}
-	next := handlers[i+1]
 
-	return next.Name()
+	return ConditionTypeDone
 }
 
-func (p *DelegateProvider) getHandler(conditionType string, handlers []Handler) Handler {
+func (p *DelegateProvider) getHandler(pipeline string, conditionType string, handlers []Handler) Handler {
 	for _, handler := range handlers {
-		if conditionType == handler.Name() {
+		if conditionType == conditionTypeOf(pipeline, handler) {
 			return handler
 		}
 	}
@@ -242,7 +358,10 @@ func (p *DelegateProvider) getHandler(conditionType string, handlers []Handler)
 	return nil
 }
 
-func (p *DelegateProvider) getCurrentCondition(c *v1alpha1.Cluster, phase v1alpha1.ClusterPhase, handlers []Handler) (*v1alpha1.ClusterCondition, error) {
+// getCurrentCondition returns the first condition of the pipeline which is not done, the
+// first step of the pipeline once all of them are done. The conditions of the other
+// pipelines are left alone.
+func (p *DelegateProvider) getCurrentCondition(c *v1alpha1.Cluster, phase v1alpha1.ClusterPhase, pipeline string, handlers []Handler) (*v1alpha1.ClusterCondition, error) {
 	if c.Status.Phase != phase {
 		return nil, fmt.Errorf("cluster phase is %s now", phase)
 	}
@@ -250,27 +369,33 @@ func (p *DelegateProvider) getCurrentCondition(c *v1alpha1.Cluster, phase v1alph
 		return nil, fmt.Errorf("no handlers")
 	}
 
-	if len(c.Status.Conditions) == 0 {
-		return &v1alpha1.ClusterCondition{
-			Type:    handlers[0].Name(),
-			Status:  v1alpha1.ConditionUnknown,
-			Message: "waiting process",
-			Reason:  ReasonWaiting,
-		}, nil
-	}
 	for _, condition := range c.Status.Conditions {
+		if !strings.HasPrefix(condition.Type, pipeline+".") {
+			continue
+		}
 		if condition.Status == v1alpha1.ConditionFalse || condition.Status == v1alpha1.ConditionUnknown {
 			return &condition, nil
 		}
 	}
-	if c.Status.Phase == v1alpha1.ClusterUpgrading ||
-		c.Status.Phase == v1alpha1.ClusterRunning {
-		return &v1alpha1.ClusterCondition{
-			Type:    handlers[0].Name(),
-			Status:  v1alpha1.ConditionUnknown,
-			Message: "waiting process",
-			Reason:  ReasonWaiting,
-		}, nil
+	if c.Status.Phase == v1alpha1.ClusterInitializing && hasPipelineConditions(c, pipeline) {
+		return nil, errors.New("no condition need process")
 	}
-	return nil, errors.New("no condition need process")
+
+	return &v1alpha1.ClusterCondition{
+		Type:    conditionTypeOf(pipeline, handlers[0]),
+		Status:  v1alpha1.ConditionUnknown,
+		Message: "waiting process",
+		Reason:  ReasonWaiting,
+	}, nil
+}
+
+// hasPipelineConditions tells whether the cluster has conditions of the pipeline.
+func hasPipelineConditions(c *v1alpha1.Cluster, pipeline string) bool {
+	for _, condition := range c.Status.Conditions {
+		if strings.HasPrefix(condition.Type, pipeline+".") {
+			return true
+		}
+	}
+
+	return false
 }
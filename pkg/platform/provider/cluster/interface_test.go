@@ -0,0 +1,145 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1alpha1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	types "pml.io/april/pkg/platform/provider/type"
+)
+
+type steps struct {
+	called []string
+}
+
+func (s *steps) EnsureFirst(ctx context.Context, c *types.Cluster) error {
+	s.called = append(s.called, "EnsureFirst")
+	return nil
+}
+
+func (s *steps) EnsureSecond(ctx context.Context, c *types.Cluster) error {
+	s.called = append(s.called, "EnsureSecond")
+	return nil
+}
+
+func (s *steps) EnsureFailed(ctx context.Context, c *types.Cluster) error {
+	s.called = append(s.called, "EnsureFailed")
+	return errors.New("not ready")
+}
+
+func TestOnCreateFailedHandler(t *testing.T) {
+	s := &steps{}
+	p := &DelegateProvider{
+		CreateHandlers: []Handler{s.EnsureFirst, s.EnsureFailed},
+	}
+	c := &types.Cluster{TargetCluster: &v1alpha1.Cluster{
+		Status: v1alpha1.ClusterStatus{Phase: v1alpha1.ClusterInitializing},
+	}}
+
+	ctx := context.Background()
+	assert.NoError(t, p.OnCreate(ctx, c))
+	// the error stops the loop of the controller, which requeues the cluster.
+	assert.Error(t, p.OnCreate(ctx, c))
+	assert.Equal(t, v1alpha1.ClusterInitializing, c.TargetCluster.Status.Phase)
+	condition := c.TargetCluster.Status.Conditions[len(c.TargetCluster.Status.Conditions)-1]
+	assert.Equal(t, "Create.EnsureFailed", condition.Type)
+	assert.Equal(t, v1alpha1.ConditionFalse, condition.Status)
+
+	// the failed handler is the one retried.
+	assert.Error(t, p.OnCreate(ctx, c))
+	assert.Equal(t, []string{"EnsureFirst", "EnsureFailed", "EnsureFailed"}, s.called)
+}
+
+func TestOnUpdateUpgrade(t *testing.T) {
+	s := &steps{}
+	p := &DelegateProvider{
+		UpgradeHandlers: []Handler{s.EnsureFirst, s.EnsureSecond},
+	}
+	c := &types.Cluster{TargetCluster: &v1alpha1.Cluster{
+		Spec:   v1alpha1.ClusterSpec{Version: "1.19.3"},
+		Status: v1alpha1.ClusterStatus{Phase: v1alpha1.ClusterRunning, Version: "1.18.9"},
+	}}
+
+	ctx := context.Background()
+	assert.NoError(t, p.OnUpdate(ctx, c))
+	assert.Equal(t, v1alpha1.ClusterUpgrading, c.TargetCluster.Status.Phase)
+	assert.Empty(t, s.called)
+
+	assert.NoError(t, p.OnUpdate(ctx, c))
+	assert.NoError(t, p.OnUpdate(ctx, c))
+	assert.Equal(t, []string{"EnsureFirst", "EnsureSecond"}, s.called)
+	assert.Equal(t, v1alpha1.ClusterRunning, c.TargetCluster.Status.Phase)
+	assert.Equal(t, "1.19.3", c.TargetCluster.Status.Version)
+
+	// nothing left to do, the update handlers run instead.
+	assert.NoError(t, p.OnUpdate(ctx, c))
+	assert.Equal(t, v1alpha1.ClusterRunning, c.TargetCluster.Status.Phase)
+	assert.Len(t, s.called, 2)
+}
+
+func TestOnUpdateUpgradeSharedHandler(t *testing.T) {
+	s := &steps{}
+	p := &DelegateProvider{
+		CreateHandlers:  []Handler{s.EnsureFirst, s.EnsureSecond},
+		UpgradeHandlers: []Handler{s.EnsureSecond},
+	}
+	c := &types.Cluster{TargetCluster: &v1alpha1.Cluster{
+		Spec:   v1alpha1.ClusterSpec{Version: "1.18.9"},
+		Status: v1alpha1.ClusterStatus{Phase: v1alpha1.ClusterInitializing},
+	}}
+
+	ctx := context.Background()
+	assert.NoError(t, p.OnCreate(ctx, c))
+	assert.NoError(t, p.OnCreate(ctx, c))
+	assert.Equal(t, v1alpha1.ClusterRunning, c.TargetCluster.Status.Phase)
+
+	// the finished create step doesn't stand for the upgrade step of the same handler.
+	c.TargetCluster.Spec.Version = "1.19.3"
+	assert.NoError(t, p.OnUpdate(ctx, c))
+	assert.NoError(t, p.OnUpdate(ctx, c))
+	assert.Equal(t, []string{"EnsureFirst", "EnsureSecond", "EnsureSecond"}, s.called)
+	assert.Equal(t, "1.19.3", c.TargetCluster.Status.Version)
+
+	var conditionTypes []string
+	for _, condition := range c.TargetCluster.Status.Conditions {
+		conditionTypes = append(conditionTypes, condition.Type)
+	}
+	assert.Contains(t, conditionTypes, "Create.EnsureSecond")
+	assert.Contains(t, conditionTypes, "Upgrade.EnsureSecond")
+}
+
+func TestDesiredPhase(t *testing.T) {
+	noop := func(ctx context.Context, c *types.Cluster) error { return nil }
+	three := int32(3)
+	p := &DelegateProvider{ScaleUpHandlers: []Handler{noop}}
+
+	c := &v1alpha1.Cluster{
+		Spec:   v1alpha1.ClusterSpec{Version: "1.19.3", MachineCount: &three},
+		Status: v1alpha1.ClusterStatus{Phase: v1alpha1.ClusterRunning, Version: "1.18.9", MachineCount: 1},
+	}
+	// upgrade has no handlers, so only the scale up is started.
+	assert.Equal(t, v1alpha1.ClusterUpscaling, p.desiredPhase(c))
+
+	c.Status.MachineCount = 5
+	assert.Equal(t, v1alpha1.ClusterRunning, p.desiredPhase(c))
+}
@@ -0,0 +1,151 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
+	"pml.io/april/pkg/platform/provider/imported/constants"
+	typesv1 "pml.io/april/pkg/platform/provider/type"
+)
+
+// cniDaemonSets maps the kube-system daemonset name prefix of well known network plugins to the plugin name.
+var cniDaemonSets = []struct {
+	prefix string
+	cni    string
+}{
+	{"calico-node", "calico"},
+	{"canal", "canal"},
+	{"kube-flannel", "flannel"},
+	{"cilium", "cilium"},
+	{"weave-net", "weave"},
+	{"kube-ovn-cni", "kube-ovn"},
+	{"antrea-agent", "antrea"},
+}
+
+// EnsureClusterCapability probes the cluster and records the result in its status.
+// A probe younger than CapabilityProbeInterval is kept as is.
+func (p *Provider) EnsureClusterCapability(ctx context.Context, c *typesv1.Cluster) error {
+	capability := c.TargetCluster.Status.Capability
+	if capability != nil && time.Since(capability.LastProbeTime.Time) < constants.CapabilityProbeInterval {
+		return nil
+	}
+	client, err := kubernetes.NewForConfig(c.TargetConfig)
+	if err != nil {
+		return err
+	}
+	capability, err = probeCapability(ctx, client)
+	if err != nil {
+		return err
+	}
+	c.TargetCluster.Status.Capability = capability
+
+	return nil
+}
+
+func probeCapability(ctx context.Context, client kubernetes.Interface) (*platformv1.ClusterCapability, error) {
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "get server version")
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+	allocatable := corev1.ResourceList{}
+	runtimes := sets.NewString()
+	for _, node := range nodes.Items {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, constants.GPUResourceName} {
+			quantity, ok := node.Status.Allocatable[name]
+			if !ok {
+				continue
+			}
+			total := allocatable[name]
+			total.Add(quantity)
+			allocatable[name] = total
+		}
+		if runtime := node.Status.NodeInfo.ContainerRuntimeVersion; runtime != "" {
+			runtimes.Insert(runtime)
+		}
+	}
+
+	storageClasses, err := client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list storage classes")
+	}
+	var storageClassNames []string
+	for _, sc := range storageClasses.Items {
+		storageClassNames = append(storageClassNames, sc.Name)
+	}
+	sort.Strings(storageClassNames)
+
+	groups, err := client.Discovery().ServerGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "get server groups")
+	}
+	var groupNames []string
+	for _, group := range groups.Groups {
+		groupNames = append(groupNames, group.Name)
+	}
+	sort.Strings(groupNames)
+
+	cni, err := detectCNI(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &platformv1.ClusterCapability{
+		LastProbeTime:     metav1.Now(),
+		Version:           version.GitVersion,
+		NodeCount:         int32(len(nodes.Items)),
+		Allocatable:       allocatable,
+		CNI:               cni,
+		ContainerRuntimes: runtimes.List(),
+		StorageClasses:    storageClassNames,
+		APIGroups:         groupNames,
+	}, nil
+}
+
+// detectCNI guesses the network plugin from the daemonsets in kube-system, an
+// unknown plugin is reported as empty.
+func detectCNI(ctx context.Context, client kubernetes.Interface) (string, error) {
+	daemonSets, err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "list kube-system daemonsets")
+	}
+	for _, known := range cniDaemonSets {
+		for _, ds := range daemonSets.Items {
+			if strings.HasPrefix(ds.Name, known.prefix) {
+				return known.cni, nil
+			}
+		}
+	}
+
+	return "", nil
+}
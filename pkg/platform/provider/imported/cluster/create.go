@@ -19,20 +19,28 @@
 package cluster
 
 import (
+	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"context"
+	"fmt"
 	"github.com/pkg/errors"
 	appv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
+	platformv1 "pml.io/april/pkg/apis/platform/v1alpha1"
 	"pml.io/april/pkg/platform/provider/imported/constants"
 
 	typesv1 "pml.io/april/pkg/platform/provider/type"
 	apiclient "pml.io/april/pkg/util/apiclient"
+	"sort"
 )
 
 func (p *Provider) EnsureClusterFittness(ctx context.Context, c *typesv1.Cluster) error {
+	if c.TargetConfig == nil {
+		return fmt.Errorf("kubeconfig of cluster %s not found", c.ClusterName)
+	}
 	client, err := kubernetes.NewForConfig(c.TargetConfig)
 	if err != nil {
 		return err
@@ -47,23 +55,125 @@ func (p *Provider) EnsureClusterFittness(ctx context.Context, c *typesv1.Cluster
 }
 
 func (p *Provider) EnsureVKInstalled(ctx context.Context, c *typesv1.Cluster) error {
-	VkDeployment := newDeployment(c.ClusterName, c.TargetCluster.Spec.KubeconfigSecret.Name)
-	_, err := c.MasterKubeclientset.AppsV1().Deployments(constants.ClusterConfigNamespace).Create(ctx, VkDeployment, metav1.CreateOptions{})
+	var spec platformv1.VirtualKubeletSpec
+	if c.TargetCluster.Spec.VirtualKubelet != nil {
+		spec = *c.TargetCluster.Spec.VirtualKubelet
+	}
+	key := c.TargetCluster.Spec.KubeconfigSecret.Key
+	if key == "" {
+		key = constants.KubeconfigKey
+	}
+	vkDeployment := newDeployment(c.ClusterName, c.TargetCluster.Spec.KubeconfigSecret.Name, key, spec)
+
+	// updating the pod template rolls the virtual-kubelet when the spec changes.
+	return apiclient.CreateOrUpdateDeployment(ctx, c.MasterKubeclientset, vkDeployment)
+}
+
+// EnsureVKReady checks the virtual-kubelet once instead of blocking the worker, the
+// failed step stops the pipeline and the cluster is requeued with a backoff until the
+// virtual-kubelet is ready.
+func (p *Provider) EnsureVKReady(ctx context.Context, c *typesv1.Cluster) error {
+	ok, err := apiclient.CheckDeployment(ctx, c.MasterKubeclientset, constants.ClusterConfigNamespace, c.ClusterName)
 	if err != nil {
-		return err
+		return fmt.Errorf("check virtual-kubelet %s error: %w", c.ClusterName, err)
+	}
+	if !ok {
+		return fmt.Errorf("virtual-kubelet %s is not ready", c.ClusterName)
 	}
-	// TODO 讨论：我们应该等待deployment 部署成功么？
-	// 可能会牺牲 并发性。
-	//if err := waitForDeploymentCompleteAndMarkPodsReady(); err != nil {
-	//	return err
-	//}
 
 	return nil
 }
 
+// EnsureTargetRegistered creates the multicluster Target of the cluster, so that
+// machines can be scheduled to it by name. The Target and its kubeconfig secret
+// are owned by the cluster.
+func (p *Provider) EnsureTargetRegistered(ctx context.Context, c *typesv1.Cluster) error {
+	key := c.TargetCluster.Spec.KubeconfigSecret.Key
+	if key == "" {
+		key = constants.KubeconfigKey
+	}
+	cm, err := c.MasterKubeclientset.CoreV1().ConfigMaps(constants.ClusterConfigNamespace).Get(ctx, c.TargetCluster.Spec.KubeconfigSecret.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	kubeconfig, ok := cm.Data[key]
+	if !ok {
+		return fmt.Errorf("key %q not found in configmap %s/%s", key, cm.Namespace, cm.Name)
+	}
+
+	ownerRef := metav1.NewControllerRef(c.TargetCluster, platformv1.SchemeGroupVersion.WithKind("Cluster"))
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       v1.NamespaceDefault,
+			Name:            targetSecretName(c.ClusterName),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Data: map[string][]byte{constants.TargetKubeconfigKey: []byte(kubeconfig)},
+	}
+	if err := apiclient.CreateOrUpdateSecret(ctx, c.MasterKubeclientset, secret); err != nil {
+		return err
+	}
+
+	target := &multiclusterv1alpha1.Target{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       v1.NamespaceDefault,
+			Name:            c.ClusterName,
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Spec: multiclusterv1alpha1.TargetSpec{
+			KubeconfigSecret: &multiclusterv1alpha1.KubeconfigSecret{
+				Name:    secret.Name,
+				Key:     constants.TargetKubeconfigKey,
+				Context: c.TargetCluster.Spec.KubeconfigSecret.Context,
+			},
+		},
+	}
+	targets := c.MasterMulticlusterClientset.MulticlusterV1alpha1().Targets(v1.NamespaceDefault)
+	existing, err := targets.Get(ctx, target.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		_, err = targets.Create(ctx, target, metav1.CreateOptions{})
+		return err
+	}
+	existing.OwnerReferences = target.OwnerReferences
+	existing.Spec = target.Spec
+	_, err = targets.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func targetSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-kubeconfig", clusterName)
+}
+
 // newDeployment returns a Deployment with a tensile-kube/virtual-kubelet image
-func newDeployment(deploymentName, cmName string) *appv1.Deployment {
+func newDeployment(deploymentName, cmName, cmKey string, spec platformv1.VirtualKubeletSpec) *appv1.Deployment {
 	replicas := int32(1)
+	if spec.Replicas != nil {
+		replicas = *spec.Replicas
+	}
+	image := spec.Image
+	if image == "" {
+		image = constants.VirtualKubeletImage
+	}
+	port := spec.Port
+	if port == 0 {
+		port = constants.VirtualKubeletPort
+	}
+	metricsPort := spec.MetricsPort
+	if metricsPort == 0 {
+		metricsPort = constants.VirtualKubeletMetricsPort
+	}
+	tolerations := spec.Tolerations
+	if tolerations == nil {
+		tolerations = []v1.Toleration{{Key: "role", Value: "not-vk", Operator: "Equal", Effect: "NoSchedule"}}
+	}
+	affinity := spec.Affinity
+	if affinity == nil {
+		affinity = defaultAffinity()
+	}
+
 	return &appv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
@@ -89,10 +199,10 @@ func newDeployment(deploymentName, cmName string) *appv1.Deployment {
 					Containers: []v1.Container{
 						{
 							Name:            "virtual-kubelet",
-							Image:           "lmxia/virtual-node:v0.1.1-21-ged34a840a4558a",
+							Image:           image,
 							ImagePullPolicy: v1.PullIfNotPresent,
 							Env: []v1.EnvVar{
-								{Name: "KUBELET_PORT", Value: "10450"},
+								{Name: "KUBELET_PORT", Value: fmt.Sprint(port)},
 								{Name: "DEFAULT_NODE_NAME", Value: deploymentName},
 								{Name: "VKUBELET_POD_IP",
 									ValueFrom: &v1.EnvVarSource{
@@ -102,23 +212,12 @@ func newDeployment(deploymentName, cmName string) *appv1.Deployment {
 									},
 								},
 							},
-							Args: []string{
-								"--provider=k8s",
-								"--nodename=$(DEFAULT_NODE_NAME)",
-								"--disable-taint=true",
-								"--kube-api-qps=500",
-								"--kube-api-burst=1000",
-								"--client-qps=500",
-								"--client-burst=1000",
-								"--client-kubeconfig=/root/kube.config",
-								"--klog.v=5",
-								"--log-level=debug",
-								"--metrics-addr=:10455",
-							},
+							Args:      virtualKubeletArgs(metricsPort, spec.ExtraArgs),
+							Resources: spec.Resources,
 							LivenessProbe: &v1.Probe{
 								Handler: v1.Handler{
 									TCPSocket: &v1.TCPSocketAction{
-										Port: intstr.FromInt(10455),
+										Port: intstr.FromInt(int(metricsPort)),
 									},
 								},
 								InitialDelaySeconds: 20,
@@ -133,8 +232,9 @@ func newDeployment(deploymentName, cmName string) *appv1.Deployment {
 							},
 						},
 					},
-					HostNetwork: true,
-					Tolerations: []v1.Toleration{{Key: "role", Value: "not-vk", Operator: "Equal", Effect: "NoSchedule"}},
+					HostNetwork:  true,
+					NodeSelector: spec.NodeSelector,
+					Tolerations:  tolerations,
 					Volumes: []v1.Volume{
 						{
 							VolumeSource: v1.VolumeSource{
@@ -142,46 +242,83 @@ func newDeployment(deploymentName, cmName string) *appv1.Deployment {
 									LocalObjectReference: v1.LocalObjectReference{
 										Name: cmName,
 									},
-									Items: []v1.KeyToPath{{Key: "kube.config", Path: "kube.config"}},
+									Items: []v1.KeyToPath{{Key: cmKey, Path: "kube.config"}},
 								},
 							},
 							Name: "kube",
 						},
 					},
 					ServiceAccountName: "virtual-kubelet",
-					Affinity: &v1.Affinity{
-						NodeAffinity: &v1.NodeAffinity{
-							RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
-								NodeSelectorTerms: []v1.NodeSelectorTerm{
-									{
-										MatchExpressions: []v1.NodeSelectorRequirement{
-											{
-												Key:      "type",
-												Operator: v1.NodeSelectorOpNotIn,
-												Values:   []string{"virtual-kubelet"},
-											},
-										},
-									},
-								},
+					Affinity:           affinity,
+				},
+			},
+		},
+	}
+}
+
+// virtualKubeletArgs merges the extra args into the default virtual-kubelet flags.
+// Flags are sorted so that the same spec always renders the same pod template.
+func virtualKubeletArgs(metricsPort int32, extraArgs map[string]string) []string {
+	flags := map[string]string{
+		"provider":          "k8s",
+		"nodename":          "$(DEFAULT_NODE_NAME)",
+		"disable-taint":     "true",
+		"kube-api-qps":      "500",
+		"kube-api-burst":    "1000",
+		"client-qps":        "500",
+		"client-burst":      "1000",
+		"client-kubeconfig": "/root/kube.config",
+		"klog.v":            "5",
+		"log-level":         "debug",
+		"metrics-addr":      fmt.Sprintf(":%d", metricsPort),
+	}
+	for k, v := range extraArgs {
+		flags[k] = v
+	}
+
+	names := make([]string, 0, len(flags))
+	for k := range flags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	args := make([]string, 0, len(names))
+	for _, k := range names {
+		args = append(args, fmt.Sprintf("--%s=%s", k, flags[k]))
+	}
+
+	return args
+}
+
+func defaultAffinity() *v1.Affinity {
+	return &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{
+								Key:      "type",
+								Operator: v1.NodeSelectorOpNotIn,
+								Values:   []string{"virtual-kubelet"},
 							},
 						},
-						PodAntiAffinity: &v1.PodAntiAffinity{
-							RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
-								{
-									LabelSelector: &metav1.LabelSelector{
-										MatchExpressions: []metav1.LabelSelectorRequirement{
-											{
-												Key:      "pod-type",
-												Operator: metav1.LabelSelectorOpIn,
-												Values:   []string{"virtual-kubelet"},
-											},
-										},
-									},
-									TopologyKey: "kubernetes.io/hostname",
-								},
+					},
+				},
+			},
+		},
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{
+								Key:      "pod-type",
+								Operator: metav1.LabelSelectorOpIn,
+								Values:   []string{"virtual-kubelet"},
 							},
 						},
 					},
+					TopologyKey: "kubernetes.io/hostname",
 				},
 			},
 		},
@@ -20,9 +20,26 @@ package cluster
 
 import (
 	"context"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	typesv1 "pml.io/april/pkg/platform/provider/type"
 )
 
 func (p *Provider) EnsureCleanClusterMark(ctx context.Context, c *typesv1.Cluster) error {
 	return nil
 }
+
+// EnsureTargetRemoved removes the multicluster Target of the cluster and its kubeconfig secret.
+func (p *Provider) EnsureTargetRemoved(ctx context.Context, c *typesv1.Cluster) error {
+	err := c.MasterMulticlusterClientset.MulticlusterV1alpha1().Targets(v1.NamespaceDefault).Delete(ctx, c.ClusterName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	err = c.MasterKubeclientset.CoreV1().Secrets(v1.NamespaceDefault).Delete(ctx, targetSecretName(c.ClusterName), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -45,9 +45,17 @@ func NewProvider() (*Provider, error) {
 		ProviderName: "Imported",
 		CreateHandlers: []clusterprovider.Handler{
 			p.EnsureClusterFittness,
+			p.EnsureClusterCapability,
 			p.EnsureVKInstalled,
+			p.EnsureVKReady,
+			p.EnsureTargetRegistered,
+		},
+		UpdateHandlers: []clusterprovider.Handler{
+			p.EnsureVKInstalled,
+			p.EnsureClusterCapability,
 		},
 		DeleteHandlers: []clusterprovider.Handler{
+			p.EnsureTargetRemoved,
 			p.EnsureCleanClusterMark,
 		},
 	}
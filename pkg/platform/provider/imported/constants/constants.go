@@ -1,9 +1,28 @@
 package constants
 
+import "time"
+
 const (
 	APIServerHostName = "pml.io"
 
 	ClusterConfigNamespace = "pml-system"
 
+	// KubeconfigKey is the default key of the kubeconfig in the cluster config map.
+	KubeconfigKey = "kube.config"
+
+	// TargetKubeconfigKey is the key of the kubeconfig in the secret referenced by a multicluster Target.
+	TargetKubeconfigKey = "config"
+
 	LabelMachineIPV4 = "pml.io/machine-ip"
+
+	// Defaults of the virtual-kubelet deployment which represents an imported cluster.
+	VirtualKubeletImage       = "lmxia/virtual-node:v0.1.1-21-ged34a840a4558a"
+	VirtualKubeletPort        = 10450
+	VirtualKubeletMetricsPort = 10455
+
+	// CapabilityProbeInterval is how often the capability of a running cluster is refreshed.
+	CapabilityProbeInterval = 10 * time.Minute
+
+	// GPUResourceName is the extended resource advertised by the nvidia device plugin.
+	GPUResourceName = "nvidia.com/gpu"
 )
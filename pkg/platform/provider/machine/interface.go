@@ -142,8 +142,9 @@ func (p *DelegateProvider) OnCreate(ctx context.Context, machine *platform.Machi
 	return nil
 }
 
+// OnUpdate runs on every update of a running machine, so the update handlers have to be idempotent.
 func (p *DelegateProvider) OnUpdate(ctx context.Context, machine *platform.Machine, cluster *typesv1.Cluster) error {
-	if machine.Status.Phase != platform.MachineUpgrading {
+	if machine.Status.Phase != platform.MachineRunning && machine.Status.Phase != platform.MachineUpgrading {
 		return nil
 	}
 	for _, handler := range p.UpdateHandlers {
@@ -171,7 +172,15 @@ func (p *DelegateProvider) OnDelete(ctx context.Context, machine *platform.Machi
 		startTime := time.Now()
 		err := handler(ctx, machine, cluster)
 		log.FromContext(ctx).Info("Done", "error", err, "cost", time.Since(startTime).String())
+		if err != nil {
+			machine.Status.Reason = ReasonFailedDelete
+			machine.Status.Message = fmt.Sprintf("%s error: %v", handler.Name(), err)
+			return err
+		}
 	}
+	machine.Status.Reason = ""
+	machine.Status.Message = ""
+
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package types
 
 import (
+	multiclusterclientset "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
 	"fmt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -8,17 +9,20 @@ import (
 	"k8s.io/klog"
 	"net/url"
 	platform "pml.io/april/pkg/apis/platform/v1alpha1"
+	platformclientset "pml.io/april/pkg/generated/clientset/versioned"
 )
 import "context"
 
 type Cluster struct {
-	K8sVersionsWithV    string
-	MasterIp            string
-	ClusterName         string
-	MasterKubeclientset *kubernetes.Clientset
-	TargetCluster       *platform.Cluster
-	TargetConfig        *rest.Config
-	ClusterCredential   *ClusterCredential
+	K8sVersionsWithV            string
+	MasterIp                    string
+	ClusterName                 string
+	MasterKubeclientset         *kubernetes.Clientset
+	MasterMulticlusterClientset multiclusterclientset.Interface
+	MasterPlatformClientset     platformclientset.Interface
+	TargetCluster               *platform.Cluster
+	TargetConfig                *rest.Config
+	ClusterCredential           *ClusterCredential
 }
 
 // ClusterCredential records the credential information needed to access the cluster.
@@ -94,11 +98,13 @@ func GetClusterByName(ctx context.Context, clusterName string, targetConfig *res
 	}, nil
 }
 
-func GetCluster(cfg *rest.Config, cluster *platform.Cluster, kubeclientset *kubernetes.Clientset) (*Cluster, error) {
+func GetCluster(cfg *rest.Config, cluster *platform.Cluster, kubeclientset *kubernetes.Clientset,
+	multiclusterclientset multiclusterclientset.Interface) (*Cluster, error) {
 	result := new(Cluster)
 	result.ClusterName = cluster.Name
 	result.TargetConfig = cfg
 	result.TargetCluster = cluster
 	result.MasterKubeclientset = kubeclientset
+	result.MasterMulticlusterClientset = multiclusterclientset
 	return result, nil
 }
@@ -0,0 +1,461 @@
+package apiclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"pml.io/april/pkg/util/template"
+)
+
+// FieldManager owns the fields april applies.
+const FieldManager = "april"
+
+var (
+	namespaceKind = schema.GroupKind{Kind: "Namespace"}
+	crdKind       = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+	// pruneKinds are looked for objects to prune besides the kinds of the manifest, so
+	// that an object is pruned even if the manifest has no object of its kind any more.
+	pruneKinds = []schema.GroupVersionKind{
+		{Version: "v1", Kind: "ConfigMap"},
+		{Version: "v1", Kind: "Secret"},
+		{Version: "v1", Kind: "Service"},
+		{Version: "v1", Kind: "ServiceAccount"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+		{Group: "apiregistration.k8s.io", Version: "v1", Kind: "APIService"},
+	}
+)
+
+// Applier applies manifests of any kind the cluster serves, custom resources included,
+// with server-side apply.
+type Applier struct {
+	client       dynamic.Interface
+	mapper       *restmapper.DeferredDiscoveryRESTMapper
+	fieldManager string
+}
+
+// ApplyOptions tells how the objects of a manifest are applied.
+type ApplyOptions struct {
+	// PruneLabels are set on the objects, and the objects of the labels which are not in
+	// the manifest any more are deleted. Nothing is pruned if empty.
+	PruneLabels map[string]string
+	// Timeout of waiting for the objects to be ready, the objects are not waited for if zero.
+	Timeout time.Duration
+}
+
+// NewApplier returns an applier of the cluster, the fields applied are owned by the
+// field manager, FieldManager if empty.
+func NewApplier(config *rest.Config, fieldManager string) (*Applier, error) {
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if fieldManager == "" {
+		fieldManager = FieldManager
+	}
+
+	return &Applier{
+		client:       client,
+		mapper:       restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		fieldManager: fieldManager,
+	}, nil
+}
+
+// RenderFile renders the template file with the option, the file is read as is if the
+// option is nil.
+func RenderFile(filename string, option interface{}) ([]byte, error) {
+	if option != nil {
+		return template.ParseFile(filename, option)
+	}
+
+	return ioutil.ReadFile(filename)
+}
+
+// ParseManifest returns the objects of the yaml or json documents of the manifest, the
+// items of a list are returned in place of it and the empty documents are skipped.
+func ParseManifest(data []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read manifest")
+		}
+		data, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse manifest")
+		}
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(data, &typeMeta); err != nil {
+			return nil, errors.Wrap(err, "unable to parse manifest")
+		}
+		if typeMeta.Kind == "" {
+			// a document of comments only
+			continue
+		}
+		obj := new(unstructured.Unstructured)
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode %s", typeMeta.Kind)
+		}
+		if !obj.IsList() {
+			objs = append(objs, obj)
+			continue
+		}
+		err = obj.EachListItem(func(item runtime.Object) error {
+			objs = append(objs, item.(*unstructured.Unstructured))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return objs, nil
+}
+
+// Apply applies the objects of the manifest. The namespaces and the custom resource
+// definitions are applied first, and the definitions are waited for to be established
+// so that their custom resources in the manifest can be applied.
+func (a *Applier) Apply(ctx context.Context, data []byte, opts ApplyOptions) error {
+	objs, err := ParseManifest(data)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if len(opts.PruneLabels) > 0 {
+			objLabels := obj.GetLabels()
+			if objLabels == nil {
+				objLabels = make(map[string]string)
+			}
+			for k, v := range opts.PruneLabels {
+				objLabels[k] = v
+			}
+			obj.SetLabels(objLabels)
+		}
+	}
+
+	for _, first := range []bool{true, false} {
+		for _, obj := range objs {
+			gk := obj.GroupVersionKind().GroupKind()
+			if (gk == namespaceKind || gk == crdKind) != first {
+				continue
+			}
+			if err := a.applyObject(ctx, obj); err != nil {
+				return err
+			}
+			if gk == crdKind {
+				if err := a.waitReady(ctx, obj, time.Minute); err != nil {
+					return err
+				}
+				a.mapper.Reset()
+			}
+		}
+	}
+
+	if len(opts.PruneLabels) > 0 {
+		if err := a.prune(ctx, objs, opts.PruneLabels); err != nil {
+			return err
+		}
+	}
+	if opts.Timeout > 0 {
+		for _, obj := range objs {
+			if err := a.waitReady(ctx, obj, opts.Timeout); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes the objects of the manifest in the reverse order, the objects which
+// are gone already and the objects of the kinds the cluster doesn't serve are skipped.
+func (a *Applier) Delete(ctx context.Context, data []byte) error {
+	objs, err := ParseManifest(data)
+	if err != nil {
+		return err
+	}
+	for i := len(objs) - 1; i >= 0; i-- {
+		r, err := a.resource(objs[i])
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := a.delete(ctx, r, objs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Applier) applyObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	r, err := a.resource(obj)
+	if err != nil {
+		return err
+	}
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	force := true
+	_, err = r.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: a.fieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to apply %s", describe(obj))
+	}
+
+	return nil
+}
+
+func (a *Applier) delete(ctx context.Context, r dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	propagation := metav1.DeletePropagationBackground
+	err := r.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "unable to delete %s", describe(obj))
+	}
+
+	return nil
+}
+
+// resource returns the client of the resource of the object.
+func (a *Applier) resource(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	mapping, err := a.restMapping(obj.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return a.client.Resource(mapping.Resource), nil
+	}
+
+	return a.client.Resource(mapping.Resource).Namespace(namespaceOf(obj)), nil
+}
+
+// restMapping returns the mapping of the kind, which is discovered again if the kind is
+// not known.
+func (a *Applier) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the kind may be served since the last discovery.
+		a.mapper.Reset()
+		mapping, err = a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+
+	return mapping, err
+}
+
+// prune deletes the objects of the labels which are not among the objects applied, the
+// objects of namespaced kinds are only looked for in the namespaces of the objects
+// applied.
+func (a *Applier) prune(ctx context.Context, applied []*unstructured.Unstructured, pruneLabels map[string]string) error {
+	keep := make(map[string]bool, len(applied))
+	namespaces := sets.NewString()
+	kinds := append([]schema.GroupVersionKind(nil), pruneKinds...)
+	seen := make(map[schema.GroupKind]bool)
+	for _, gvk := range kinds {
+		seen[gvk.GroupKind()] = true
+	}
+	for _, obj := range applied {
+		gvk := obj.GroupVersionKind()
+		mapping, err := a.restMapping(gvk)
+		if err != nil {
+			return err
+		}
+		var namespace string
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespace = namespaceOf(obj)
+			namespaces.Insert(namespace)
+		}
+		keep[objectKey(gvk.GroupKind(), namespace, obj.GetName())] = true
+		if !seen[gvk.GroupKind()] {
+			seen[gvk.GroupKind()] = true
+			kinds = append(kinds, gvk)
+		}
+	}
+
+	selector := labels.SelectorFromSet(pruneLabels).String()
+	for _, gvk := range kinds {
+		mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		resources := []dynamic.ResourceInterface{a.client.Resource(mapping.Resource)}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resources = nil
+			for _, namespace := range namespaces.List() {
+				resources = append(resources, a.client.Resource(mapping.Resource).Namespace(namespace))
+			}
+		}
+		for _, r := range resources {
+			list, err := r.List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return errors.Wrapf(err, "unable to list %s", mapping.Resource.String())
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				obj.SetGroupVersionKind(gvk)
+				if keep[objectKey(gvk.GroupKind(), obj.GetNamespace(), obj.GetName())] {
+					continue
+				}
+				if err := a.delete(ctx, r, obj); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitReady waits for the object to be ready, the objects of the kinds without a
+// readiness are ready once applied.
+func (a *Applier) waitReady(ctx context.Context, obj *unstructured.Unstructured, timeout time.Duration) error {
+	r, err := a.resource(obj)
+	if err != nil {
+		return err
+	}
+	var reason string
+	err = wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		current, err := r.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			reason = err.Error()
+			return false, nil
+		}
+		reason = notReadyReason(current)
+		return reason == "", nil
+	})
+	if err != nil {
+		return errors.Errorf("%s is not ready: %s", describe(obj), reason)
+	}
+
+	return nil
+}
+
+// notReadyReason tells why the object is not ready, empty if it is ready.
+func notReadyReason(obj *unstructured.Unstructured) string {
+	generation := obj.GetGeneration()
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	status := func(field string) int64 {
+		v, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+		return v
+	}
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		if observed < generation {
+			return "the spec is not observed yet"
+		}
+		if status("updatedReplicas") < replicas || status("availableReplicas") < replicas {
+			return fmt.Sprintf("%d of %d replicas are updated and available", status("availableReplicas"), replicas)
+		}
+	case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+		if observed < generation {
+			return "the spec is not observed yet"
+		}
+		if status("readyReplicas") < replicas {
+			return fmt.Sprintf("%d of %d replicas are ready", status("readyReplicas"), replicas)
+		}
+	case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}:
+		if observed < generation {
+			return "the spec is not observed yet"
+		}
+		desired := status("desiredNumberScheduled")
+		if status("updatedNumberScheduled") < desired || status("numberReady") < desired {
+			return fmt.Sprintf("%d of %d pods are updated and ready", status("numberReady"), desired)
+		}
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		completions, found, _ := unstructured.NestedInt64(obj.Object, "spec", "completions")
+		if !found {
+			completions = 1
+		}
+		if status("succeeded") < completions {
+			return fmt.Sprintf("%d of %d completions succeeded", status("succeeded"), completions)
+		}
+	case crdKind:
+		if !conditionTrue(obj, "Established") {
+			return "the definition is not established"
+		}
+	}
+
+	return ""
+}
+
+func conditionTrue(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, one := range conditions {
+		condition, ok := one.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			return condition["status"] == "True"
+		}
+	}
+
+	return false
+}
+
+func objectKey(gk schema.GroupKind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", gk, namespace, name)
+}
+
+// namespaceOf returns the namespace of an object of a namespaced kind, the default
+// namespace if it has none.
+func namespaceOf(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return metav1.NamespaceDefault
+	}
+	return obj.GetNamespace()
+}
+
+func describe(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
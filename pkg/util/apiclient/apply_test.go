@@ -0,0 +1,49 @@
+package apiclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest := `# comments only
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: script
+  namespace: kube-system
+data:
+  run.sh: |
+    cat <<EOF
+    ---
+    EOF
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: addon
+- apiVersion: example.com/v1
+  kind: Widget
+  metadata:
+    name: widget
+  spec:
+    replicas: 2
+`
+	objs, err := ParseManifest([]byte(manifest))
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 3) {
+		assert.Equal(t, "ConfigMap", objs[0].GetKind())
+		assert.Equal(t, "cat <<EOF\n---\nEOF\n", objs[0].Object["data"].(map[string]interface{})["run.sh"])
+		assert.Equal(t, "ServiceAccount", objs[1].GetKind())
+		assert.Equal(t, "example.com", objs[2].GroupVersionKind().Group)
+		assert.Equal(t, int64(2), objs[2].Object["spec"].(map[string]interface{})["replicas"])
+	}
+
+	_, err = ParseManifest([]byte("kind: [\n"))
+	assert.Error(t, err)
+}
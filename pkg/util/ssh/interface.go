@@ -28,6 +28,8 @@ type Interface interface {
 	Exec(cmd string) (stdout string, stderr string, exit int, err error)
 
 	CopyFile(src, dst string) error
+	// UploadFile copies a large file, resuming where a broken off upload stopped.
+	UploadFile(src, dst string) error
 	WriteFile(src io.Reader, dst string) error
 	ReadFile(filename string) ([]byte, error)
 	Exist(filename string) (bool, error)
@@ -0,0 +1,175 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack
+ * available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the “License”); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an “AS IS” BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"pml.io/april/pkg/util/log"
+)
+
+const (
+	uploadChunkSize = 4 << 20
+)
+
+// uploadBackoff is how a broken off upload is retried, each try resumes where the
+// previous one stopped.
+var uploadBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Steps:    8,
+	Cap:      time.Minute,
+}
+
+// UploadFile copies the local file to dst in chunks. The chunks are appended to a
+// staging file named by the sha256 of the content, so that an upload broken off is
+// resumed from its last chunk by the retries, or by the next call. The staging file
+// is moved to dst once its sum matches.
+func (s *SSH) UploadFile(src, dst string) error {
+	sum, size, err := fileSHA256(src)
+	if err != nil {
+		return err
+	}
+	if s.sha256Matches(dst, sum) {
+		log.Debugf("[%s] Skip upload %q because already existed", s.addr(), dst)
+		return nil
+	}
+
+	staging := path.Join(tmpDir, "upload-"+sum)
+	var uploadErr error
+	err = wait.ExponentialBackoff(uploadBackoff, func() (bool, error) {
+		if uploadErr = s.upload(src, staging, size); uploadErr != nil {
+			log.Warnf("[%s] Upload %q broken off, will resume: %v", s.addr(), src, uploadErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("upload %s error: %w", src, uploadErr)
+	}
+
+	if !s.sha256Matches(staging, sum) {
+		// the staging file is corrupt, the next call starts over.
+		_, _ = s.CombinedOutput(fmt.Sprintf("rm -f %s", staging))
+		return fmt.Errorf("checksum of %s mismatch after upload", staging)
+	}
+	_, err = s.CombinedOutput(fmt.Sprintf("mkdir -p $(dirname %s) && mv -f %s %s", dst, staging, dst))
+
+	return err
+}
+
+// upload appends the part of src the staging file lacks to it.
+func (s *SSH) upload(src, staging string, size int64) error {
+	sftpClient, closer, err := s.newSFTPClient()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	var offset int64
+	if info, err := sftpClient.Stat(staging); err == nil {
+		offset = info.Size()
+	}
+	if offset > size {
+		offset = 0
+	}
+
+	local, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+	if _, err := local.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	remote, err := sftpClient.OpenFile(staging, os.O_WRONLY|os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+	if err := remote.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := remote.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		log.Infof("[%s] Resume upload %q at %d/%d bytes", s.addr(), src, offset, size)
+	}
+	buf := make([]byte, uploadChunkSize)
+	lastReported := offset * 10 / max(size, 1)
+	for offset < size {
+		n, err := io.ReadFull(local, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if _, err := remote.Write(buf[:n]); err != nil {
+			return err
+		}
+		offset += int64(n)
+		// report each tenth of the file.
+		if reported := offset * 10 / size; reported > lastReported {
+			lastReported = reported
+			log.Infof("[%s] Upload %q: %d%% (%d/%d bytes)", s.addr(), src, offset*100/size, offset, size)
+		}
+	}
+
+	return nil
+}
+
+func (s *SSH) sha256Matches(file, sum string) bool {
+	out, err := s.CombinedOutput(fmt.Sprintf("sha256sum %s", file))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(out))
+
+	return len(fields) > 0 && fields[0] == sum
+}
+
+func fileSHA256(file string) (string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+func max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}